@@ -1,34 +1,368 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
 	"github.com/lnpay-wrapper-api-go/src/api/app/handlers"
+	"github.com/lnpay-wrapper-api-go/src/api/auth"
 	"github.com/lnpay-wrapper-api-go/src/api/config"
+	"github.com/lnpay-wrapper-api-go/src/api/controllers"
+	"github.com/lnpay-wrapper-api-go/src/api/diagnostics"
+	"github.com/lnpay-wrapper-api-go/src/api/events"
+	"github.com/lnpay-wrapper-api-go/src/api/idempotency"
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+	"github.com/lnpay-wrapper-api-go/src/api/metrics"
+	mw "github.com/lnpay-wrapper-api-go/src/api/middlewares"
+	"github.com/lnpay-wrapper-api-go/src/api/ratelimit"
+	"github.com/lnpay-wrapper-api-go/src/api/requestid"
+	"github.com/lnpay-wrapper-api-go/src/api/shutdown"
+	"github.com/lnpay-wrapper-api-go/src/api/storage"
+	"github.com/lnpay-wrapper-api-go/src/api/tracing"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/certs"
 	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/redact"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/validation"
 )
 
-var router *gin.Engine
+var (
+	router         *gin.Engine
+	apiKeys        storage.APIKeyStore
+	certIdentities auth.CertIdentities
+	wallets        storage.WalletStore
+	limiter        *ratelimit.Limiter
+	idempotent     gin.HandlerFunc
+	redisClients   = map[string]*redis.Client{}
+	invoiceHub     *events.InvoiceHub
+	webhookQueue   *events.WebhookQueue
+	stopPolling    context.CancelFunc
+	// moneyGate tracks the in-flight requests moving money, drained before shutting down.
+	moneyGate = shutdown.NewGate()
+	// upstreamErrors keeps the last failed calls to lnpay for the admin diagnostics
+	upstreamErrors = diagnostics.NewUpstreamErrors(recentUpstreamErrors)
+)
+
+// recentUpstreamErrors is how many failed calls to lnpay are kept for the admin diagnostics.
+const recentUpstreamErrors = 100
 
+// Start serves the api until it receives SIGINT or SIGTERM, then shuts it down gracefully.
 func Start() {
 	ConfigureRouter()
 
-	if err := router.Run(config.ConfMap.APIRestServerPort); err != nil {
+	server := &http.Server{
+		Addr:         config.ConfMap.APIRestServerPort,
+		Handler:      router,
+		ReadTimeout:  config.ConfMap.ReadTimeout,
+		WriteTimeout: config.ConfMap.WriteTimeout,
+		IdleTimeout:  config.ConfMap.IdleTimeout,
+	}
+	// the invoice event streams never end by themselves, closing the hub ends them
+	server.RegisterOnShutdown(invoiceHub.Close)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := configureTLS(server); err != nil {
+			serverErr <- err
+			return
+		}
+		if server.TLSConfig != nil {
+			logger.Info("Listening with TLS on " + server.Addr)
+			serverErr <- server.ListenAndServeTLS("", "")
+			return
+		}
+		logger.Info("Listening on " + server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-serverErr:
 		logger.Errorf("Error starting router", err)
+	case sig := <-signals:
+		logger.Info("Received " + sig.String() + ", shutting down")
+	}
+	gracefulShutdown(server)
+}
+
+// configureTLS enables TLS on server when a certificate is configured. The certificates and the
+// client CA bundle are loaded again every time the configuration is reloaded.
+func configureTLS(server *http.Server) error {
+	opts := tlsOptions(config.ConfMap)
+	if !opts.Enabled() {
+		return nil
+	}
+	reloader, err := certs.NewReloader(opts)
+	if err != nil {
+		return err
+	}
+	server.TLSConfig = reloader.TLSConfig()
+
+	config.Subscribe("tls", []string{"api_tls_cert_file", "api_tls_key_file", "api_tls_client_auth", "api_tls_client_ca_file"}, func(conf config.Configuration) error {
+		if err := reloader.Reload(tlsOptions(conf)); err != nil {
+			return err
+		}
+		logger.Info("TLS certificates reloaded")
+		return nil
+	})
+	return nil
+}
+
+func tlsOptions(conf config.Configuration) certs.Options {
+	return certs.Options{
+		CertFile:     conf.TLSCertFile,
+		KeyFile:      conf.TLSKeyFile,
+		ClientCAFile: conf.TLSClientCAFile,
+		ClientAuth:   conf.TLSClientAuth,
+	}
+}
+
+// gracefulShutdown stops taking money-moving requests and waits for the in-flight ones before
+// stopping the server, then flushes the webhook queue and the logs, all within the shutdown timeout.
+func gracefulShutdown(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.ConfMap.ShutdownTimeout)
+	defer cancel()
+
+	if err := moneyGate.Wait(ctx); err != nil {
+		logger.Error(fmt.Sprintf("Shutting down with %d money-moving requests in flight", moneyGate.InFlight()), err)
+	}
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("Error shutting down the server", err)
+	}
+
+	stopPolling()
+	drained := make(chan struct{})
+	go func() {
+		webhookQueue.Close()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		logger.Error(fmt.Sprintf("Shutting down with %d webhooks queued", webhookQueue.Depth()), ctx.Err())
+	}
+
+	for addr, client := range redisClients {
+		if err := client.Close(); err != nil {
+			logger.Error("Error closing redis client", err, "addr:"+addr)
+		}
+	}
+	if err := tracing.Shutdown(ctx); err != nil {
+		logger.Error("Error flushing spans", err)
+	}
+	logger.Info("Shutdown complete")
+	if err := logger.Close(); err != nil {
+		fmt.Println("Error closing the log file: ", err)
 	}
 }
 
 func ConfigureRouter() {
-	router = handlers.DefaultRouter()
-	mapUrlsToControllers()
-	logger.InitLog(logger.ConfigureLog{
-		LoggingPath:  config.ConfMap.LoggingPath,
-		LoggingFile:  config.ConfMap.LoggingFile,
-		LoggingLevel: config.ConfMap.LoggingLevel,
+	redact.Configure(config.ConfMap.RedactFields)
+	logger.InitLog(logConfig(config.ConfMap))
+	config.Subscribe("logger", []string{
+		"api_logpath", "api_logfile", "api_loglevel", "api_log_format", "api_log_sinks", "api_log_max_size_mb",
+		"api_log_rotate_interval", "api_log_max_backups", "api_log_max_age", "api_log_syslog_network",
+		"api_log_syslog_addr", "api_log_syslog_tag", "api_log_component_levels", "redact_fields",
+	}, func(conf config.Configuration) error {
+		redact.Configure(conf.RedactFields)
+		return logger.Reconfigure(logConfig(conf))
 	})
+	apierrors.ProblemTypeBase = config.ConfMap.ErrorTypeBase
+	apierrors.SetDefaultFormat(apierrors.Format(config.ConfMap.ErrorFormat))
+	err := validation.Register(amountLimits(config.ConfMap))
+	if err != nil {
+		logger.Error("Error registering the validation rules", err)
+	}
+	config.Subscribe("limits", []string{"limits_min_amount_sats", "limits_max_amount_sats"}, func(conf config.Configuration) error {
+		validation.SetLimits(amountLimits(conf))
+		return nil
+	})
+	configureTracing()
+	router = handlers.CustomRouter(handlers.RouterConfig{
+		AccessLog:      mw.AccessLogConfig{ReadSampleRate: config.ConfMap.AccessLogReadSampleRate},
+		TrustedProxies: config.ConfMap.TrustedProxies,
+	})
+	client := lnpay.NewClient(config.ConfMap.LNPayAPIKey)
+	client.SetBaseURL(config.ConfMap.LNPayBaseURL)
+	client.SetNode(config.ConfMap.LNPayNode)
+	client.SetTimeout(config.ConfMap.LNPayTimeout)
+	client.SetRetry(lnpay.Retry{Attempts: config.ConfMap.LNPayRetries, Backoff: config.ConfMap.LNPayRetryBackoff})
+	client.SetBreaker(lnpay.NewBreaker(config.ConfMap.LNPayBreakerThreshold, config.ConfMap.LNPayBreakerCooldown))
+	client.Use(requestid.LNPayInterceptor, tracing.LNPayInterceptor, metrics.LNPayInterceptor, upstreamErrors.LNPayInterceptor)
+	client.OnLockWait(metrics.LockWait)
+	wallets = storage.NewMemoryWalletStore()
+	controllers.ConfigureLNPay(client, wallets)
+	configureEvents(client)
+	configureAuth()
+	configureRateLimit()
+	configureIdempotency()
+	configureHealth(client)
+	controllers.ConfigureDiagnostics(limiter, upstreamErrors)
+	mapUrlsToControllers()
+}
+
+func amountLimits(conf config.Configuration) validation.Limits {
+	return validation.Limits{MinAmount: conf.LimitsMinAmountSats, MaxAmount: conf.LimitsMaxAmountSats}
+}
+
+func logConfig(conf config.Configuration) logger.ConfigureLog {
+	return logger.ConfigureLog{
+		LoggingPath:  conf.LoggingPath,
+		LoggingFile:  conf.LoggingFile,
+		LoggingLevel: conf.LoggingLevel,
+		Format:       conf.LoggingFormat,
+		Sinks:        conf.LoggingSinks,
+		Rotation: logger.Rotation{
+			MaxSizeMB:  conf.LoggingMaxSizeMB,
+			Interval:   conf.LoggingRotateInterval,
+			MaxBackups: conf.LoggingMaxBackups,
+			MaxAge:     conf.LoggingMaxAge,
+		},
+		Syslog: logger.Syslog{
+			Network: conf.LoggingSyslogNetwork,
+			Addr:    conf.LoggingSyslogAddr,
+			Tag:     conf.LoggingSyslogTag,
+		},
+		ComponentLevels: conf.LoggingComponentLevels,
+	}
 }
 
 func ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	router.ServeHTTP(w, req)
 }
+
+func configureTracing() {
+	onError := func(err error) {
+		logger.Error("Error exporting spans", err)
+	}
+	var processor tracing.Processor
+	switch config.ConfMap.TracingExporter {
+	case "otlp":
+		exporter := tracing.NewOTLPExporter(config.ConfMap.TracingOTLPEndpoint, config.ConfMap.TracingOTLPHeaders,
+			config.ConfMap.TracingServiceName, config.ConfMap.TracingOTLPTimeout)
+		processor = tracing.NewBatchProcessor(exporter, config.ConfMap.TracingBatchSize, config.ConfMap.TracingFlushInterval, onError)
+	}
+	tracing.Configure(tracing.NewTracer(processor))
+	tracing.PropagateToLNPay(config.ConfMap.TracingPropagateLNPay)
+	config.Subscribe("tracing", []string{"tracing_propagate_lnpay"}, func(conf config.Configuration) error {
+		tracing.PropagateToLNPay(conf.TracingPropagateLNPay)
+		return nil
+	})
+}
+
+func configureEvents(client *lnpay.Client) {
+	invoiceHub = events.NewInvoiceHub(client.Transaction, config.ConfMap.EventsMaxSubscriptions)
+	webhookQueue = events.NewWebhookQueue(invoiceHub, config.ConfMap.WebhookQueueSize, config.ConfMap.WebhookWorkers)
+	if config.ConfMap.EventsTokenSecret == "" {
+		logger.Warn("No events token secret configured, the invoice events tokens are only valid on this instance until restart")
+	}
+	tokens := events.NewSubscriptionTokens(config.ConfMap.EventsTokenSecret, config.ConfMap.EventsTokenTTL)
+	controllers.ConfigureEvents(invoiceHub, webhookQueue, tokens, config.ConfMap.EventsAllowedOrigins)
+
+	var ctx context.Context
+	ctx, stopPolling = context.WithCancel(context.Background())
+	go invoiceHub.Poll(ctx, config.ConfMap.EventsPollInterval)
+}
+
+func configureAuth() {
+	apiKeys = storage.NewMemoryAPIKeyStore()
+	controllers.ConfigureAuth(apiKeys)
+
+	certIdentities = auth.CertIdentities{}
+	for _, client := range config.ConfMap.TLSClientIdentities {
+		for _, scope := range client.Scopes {
+			if !auth.ValidScope(scope) {
+				logger.Warn("Unknown scope " + scope + " granted to the client certificate " + client.Subject)
+			}
+		}
+		certIdentities[client.Subject] = auth.Identity{
+			KeyID:   "cert:" + client.Subject,
+			Name:    client.Subject,
+			Scopes:  client.Scopes,
+			Wallets: client.Wallets,
+		}
+	}
+
+	if config.ConfMap.AuthBootstrapKey == "" {
+		logger.Warn("No bootstrap api key configured, only previously issued keys will be accepted")
+		return
+	}
+	err := apiKeys.Save(storage.APIKey{
+		ID:        "bootstrap",
+		Name:      "bootstrap",
+		Hash:      auth.HashKey(config.ConfMap.AuthBootstrapKey),
+		Scopes:    []string{auth.ScopeAdmin},
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		logger.Error("Error saving the bootstrap api key", err)
+	}
+}
+
+func configureRateLimit() {
+	var backend ratelimit.Backend
+	switch config.ConfMap.RateLimitBackend {
+	case "redis":
+		backend = ratelimit.NewRedisBackend(redisClient(config.ConfMap.RateLimitRedisAddr))
+	default:
+		backend = ratelimit.NewMemoryBackend()
+	}
+
+	limiter = ratelimit.NewLimiter(backend, rateLimitBudgets(config.ConfMap))
+	config.Subscribe("ratelimit", []string{
+		"ratelimit_period", "ratelimit_read_per_key", "ratelimit_read_per_ip", "ratelimit_money_per_key", "ratelimit_money_per_ip",
+	}, func(conf config.Configuration) error {
+		limiter.SetBudgets(rateLimitBudgets(conf))
+		return nil
+	})
+}
+
+func rateLimitBudgets(conf config.Configuration) map[ratelimit.Class]ratelimit.Budget {
+	period := conf.RateLimitPeriod
+	return map[ratelimit.Class]ratelimit.Budget{
+		ratelimit.Read: {
+			PerKey: ratelimit.Limit{Requests: conf.RateLimitReadPerKey, Period: period},
+			PerIP:  ratelimit.Limit{Requests: conf.RateLimitReadPerIP, Period: period},
+		},
+		ratelimit.Money: {
+			PerKey: ratelimit.Limit{Requests: conf.RateLimitMoneyPerKey, Period: period},
+			PerIP:  ratelimit.Limit{Requests: conf.RateLimitMoneyPerIP, Period: period},
+		},
+	}
+}
+
+func configureIdempotency() {
+	var store idempotency.Store
+	switch config.ConfMap.IdempotencyBackend {
+	case "redis":
+		store = idempotency.NewRedisStore(redisClient(config.ConfMap.IdempotencyRedisAddr))
+	default:
+		store = idempotency.NewMemoryStore()
+	}
+
+	idempotent = mw.Idempotency(store, mw.IdempotencyConfig{
+		TTL:     config.ConfMap.IdempotencyTTL,
+		LockTTL: config.ConfMap.IdempotencyLockTTL,
+		Wait:    config.ConfMap.IdempotencyWait,
+	})
+}
+
+// redisClient returns the client connected to addr, sharing it between the backends using the same server.
+func redisClient(addr string) *redis.Client {
+	if client, ok := redisClients[addr]; ok {
+		return client
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	redisClients[addr] = client
+	return client
+}
@@ -6,39 +6,50 @@ package handlers
 
 import (
 	"fmt"
-	"net/http"
-	"os"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/middlewares"
 	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-var production bool = os.Getenv("GO_ENVIRONMENT") == "production"
-
 func DefaultRouter() *gin.Engine {
-	return CustomRouter(RouterConfig{})
+	return CustomRouter(RouterConfig{AccessLog: middlewares.AccessLogConfig{ReadSampleRate: 1}})
 }
 
 func CustomRouter(conf RouterConfig) *gin.Engine {
 	router := gin.New()
+	// the client ip keys the rate limits, the forwarded headers are only trusted from the known proxies
+	if err := router.SetTrustedProxies(conf.TrustedProxies); err != nil {
+		logger.Error("Invalid trusted proxies, using the remote address as client ip", err)
+		_ = router.SetTrustedProxies(nil)
+	}
+	router.Use(middlewares.RequestID(), middlewares.AccessLog(conf.AccessLog), middlewares.Metrics(),
+		middlewares.Tracing(), middlewares.Recovery())
 
 	if !conf.DisableSwagger {
 		router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
-	if !production {
-		router.Use(gin.Logger())
-	}
 
+	router.HandleMethodNotAllowed = true
 	router.NoRoute(noRouteHandler)
+	router.NoMethod(noMethodHandler)
 	return router
 }
 
 type RouterConfig struct {
 	DisableSwagger bool
+	AccessLog      middlewares.AccessLogConfig
+	// TrustedProxies are the addresses or CIDRs allowed to set the client ip with X-Forwarded-For
+	TrustedProxies []string
 }
 
 func noRouteHandler(c *gin.Context) {
-	c.JSON(http.StatusNotFound, apierrors.NewNotFoundApiError(fmt.Sprintf("Resource %s not found.", c.Request.URL.Path)))
+	middlewares.Render(c, apierrors.NewNotFoundApiError(fmt.Sprintf("Resource %s not found.", c.Request.URL.Path)))
+}
+
+func noMethodHandler(c *gin.Context) {
+	middlewares.Render(c, apierrors.NewMethodNotAllowedApiError())
 }
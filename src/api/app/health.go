@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/lnpay-wrapper-api-go/src/api/config"
+	"github.com/lnpay-wrapper-api-go/src/api/controllers"
+	"github.com/lnpay-wrapper-api-go/src/api/health"
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+)
+
+var errShuttingDown = errors.New("shutting down")
+
+func configureHealth(client *lnpay.Client) {
+	checker := health.NewChecker(config.ConfMap.HealthTimeout,
+		health.Check{Name: "config", Critical: true, Run: checkConfig},
+		health.Check{Name: "shutdown", Critical: true, Run: checkShutdown},
+		health.Check{Name: "lnpay", CacheTTL: config.ConfMap.HealthCacheTTL, Run: checkLNPay(client)},
+		health.Check{Name: "lnpay_circuit_breaker", Run: checkBreaker(client.Breaker())},
+		health.Check{Name: "storage", Critical: true, Run: checkStorage},
+		health.Check{Name: "ratelimit", Run: checkBackend(config.ConfMap.RateLimitBackend, config.ConfMap.RateLimitRedisAddr)},
+		health.Check{Name: "idempotency", Critical: true, Run: checkBackend(config.ConfMap.IdempotencyBackend, config.ConfMap.IdempotencyRedisAddr)},
+		health.Check{Name: "webhook_queue", Run: checkWebhookQueue},
+	)
+	controllers.ConfigureHealth(checker)
+}
+
+func checkConfig(ctx context.Context) health.Result {
+	err := config.Current().Validate()
+	var invalid config.ValidationError
+	if errors.As(err, &invalid) {
+		return health.Down(err, map[string]interface{}{"problems": invalid.Problems})
+	}
+	return health.Up(nil)
+}
+
+func checkShutdown(ctx context.Context) health.Result {
+	if moneyGate.Closed() {
+		return health.Down(errShuttingDown, map[string]interface{}{"in_flight": moneyGate.InFlight()})
+	}
+	return health.Up(nil)
+}
+
+// checkLNPay calls lnpay with the configured key, an api key lnpay rejects is reported as such.
+func checkLNPay(client *lnpay.Client) func(ctx context.Context) health.Result {
+	return func(ctx context.Context) health.Result {
+		err := client.Ping(ctx)
+		var lnpayErr lnpay.Error
+		if errors.As(err, &lnpayErr) && (lnpayErr.Status == http.StatusUnauthorized || lnpayErr.Status == http.StatusForbidden) {
+			return health.Down(fmt.Errorf("lnpay rejected the api key: %w", err), nil)
+		}
+		if err != nil {
+			return health.Down(err, nil)
+		}
+		return health.Up(nil)
+	}
+}
+
+func checkBreaker(breaker *lnpay.Breaker) func(ctx context.Context) health.Result {
+	return func(ctx context.Context) health.Result {
+		if breaker == nil || breaker.Threshold <= 0 {
+			return health.Up(map[string]interface{}{"state": "disabled"})
+		}
+		status := breaker.Status()
+		switch status.State {
+		case lnpay.BreakerOpen:
+			return health.Down(lnpay.ErrCircuitOpen, status)
+		case lnpay.BreakerHalfOpen:
+			return health.Degraded("lnpay circuit breaker is half open", status)
+		default:
+			return health.Up(status)
+		}
+	}
+}
+
+func checkStorage(ctx context.Context) health.Result {
+	keys, err := apiKeys.List()
+	if err != nil {
+		return health.Down(fmt.Errorf("api keys: %w", err), nil)
+	}
+	walletRecords, err := wallets.List()
+	if err != nil {
+		return health.Down(fmt.Errorf("wallets: %w", err), nil)
+	}
+	return health.Up(map[string]interface{}{"api_keys": len(keys), "wallets": len(walletRecords)})
+}
+
+// checkBackend pings the redis server of a backend, the memory backends are always up.
+func checkBackend(backend, addr string) func(ctx context.Context) health.Result {
+	return func(ctx context.Context) health.Result {
+		details := map[string]interface{}{"backend": backend}
+		if backend != "redis" {
+			return health.Up(details)
+		}
+		details["addr"] = addr
+		if err := redisClient(addr).Ping(ctx).Err(); err != nil {
+			return health.Down(err, details)
+		}
+		return health.Up(details)
+	}
+}
+
+func checkWebhookQueue(ctx context.Context) health.Result {
+	depth, capacity := webhookQueue.Depth(), webhookQueue.Capacity()
+	details := map[string]interface{}{"depth": depth, "capacity": capacity}
+	if depth*10 >= capacity*9 {
+		return health.Degraded("webhook queue is almost full", details)
+	}
+	return health.Up(details)
+}
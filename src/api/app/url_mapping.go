@@ -1,7 +1,56 @@
 package app
 
-import "github.com/lnpay-wrapper-api-go/src/api/controllers"
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/auth"
+	"github.com/lnpay-wrapper-api-go/src/api/controllers"
+	"github.com/lnpay-wrapper-api-go/src/api/metrics"
+	mw "github.com/lnpay-wrapper-api-go/src/api/middlewares"
+	"github.com/lnpay-wrapper-api-go/src/api/ratelimit"
+)
 
 func mapUrlsToControllers() {
 	router.GET("/ping", controllers.Ping)
+	router.GET("/health/live", controllers.Live)
+	router.GET("/health/ready", controllers.Ready)
+	// prometheus scrapes the metrics with an admin api key as bearer token
+	router.GET("/metrics", mw.Authenticate(apiKeys, certIdentities), mw.RequireScope(auth.ScopeAdmin), gin.WrapH(metrics.Handler()))
+
+	read := mw.RateLimit(limiter, ratelimit.Read)
+	money := mw.RateLimit(limiter, ratelimit.Money)
+	drain := mw.Drain(moneyGate)
+
+	v1 := router.Group("/v1")
+	// lnpay calls the webhook receiver, and the invoice events are opened by the checkout frontends:
+	// they don't take an api key but the signed token returned along with the invoice.
+	v1.POST("/webhooks/lnpay", read, controllers.LNPayWebhook)
+	v1.GET("/invoices/:lntxId/events", read, controllers.InvoiceEvents)
+	v1.GET("/invoices/:lntxId/ws", read, controllers.InvoiceEventsWebsocket)
+
+	authenticated := v1.Group("", mw.Authenticate(apiKeys, certIdentities))
+	authenticated.POST("/wallets", money, mw.RequireScope(auth.ScopeAdmin), controllers.CreateWallet)
+	authenticated.GET("/wallets", read, mw.RequireScope(auth.ScopeRead), controllers.ListWallets)
+	authenticated.GET("/transactions/:lntxId", read, mw.RequireScope(auth.ScopeRead), mw.RequireAllWallets(), controllers.GetTransaction)
+	authenticated.GET("/invoices/decode", read, mw.RequireScope(auth.ScopeRead), controllers.DecodeInvoice)
+	authenticated.GET("/routes", read, mw.RequireScope(auth.ScopeRead), controllers.QueryRoutes)
+
+	wallet := authenticated.Group("/wallets/:key", mw.ValidateWalletKey(), mw.RequireWalletAccess(wallets))
+	wallet.GET("", read, mw.RequireScope(auth.ScopeRead), controllers.GetWallet)
+	wallet.GET("/transactions", read, mw.RequireScope(auth.ScopeRead), controllers.ListWalletTransactions)
+	wallet.POST("/invoices", money, mw.RequireScope(auth.ScopeInvoice), drain, idempotent, controllers.CreateInvoice)
+	wallet.POST("/payments", money, mw.RequireScope(auth.ScopePay), drain, idempotent, controllers.CreatePayment)
+	wallet.POST("/transfers", money, mw.RequireScope(auth.ScopePay), drain, idempotent, controllers.CreateTransfer)
+
+	admin := router.Group("/admin", mw.Authenticate(apiKeys, certIdentities), read, mw.RequireScope(auth.ScopeAdmin))
+	admin.POST("/keys", controllers.IssueAPIKey)
+	admin.GET("/keys", controllers.ListAPIKeys)
+	admin.DELETE("/keys/:id", controllers.RevokeAPIKey)
+	admin.GET("/log-levels", controllers.GetLogLevels)
+	admin.PUT("/log-levels", controllers.SetLogLevels)
+	admin.GET("/config", controllers.GetConfig)
+	admin.POST("/config/reload", controllers.ReloadConfig)
+	admin.GET("/wallets", controllers.ListLoadedWallets)
+	admin.GET("/diagnostics/breaker", controllers.GetBreakerStatus)
+	admin.GET("/diagnostics/ratelimit", controllers.GetRateLimits)
+	admin.GET("/diagnostics/upstream-errors", controllers.ListUpstreamErrors)
 }
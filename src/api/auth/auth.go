@@ -0,0 +1,120 @@
+/**
+* @author mnunez
+ */
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	ScopeRead    = "read"
+	ScopeInvoice = "invoice"
+	ScopePay     = "pay"
+	ScopeAdmin   = "admin"
+
+	// KeyPrefix makes the keys issued by this api easy to recognize.
+	KeyPrefix = "lwk_"
+)
+
+var Scopes = []string{ScopeRead, ScopeInvoice, ScopePay, ScopeAdmin}
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	KeyID   string
+	Name    string
+	Scopes  []string
+	Wallets []string
+}
+
+// HasScope reports whether the caller was granted scope. The admin scope grants every scope.
+func (i Identity) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccessWallet reports whether the caller may operate on the wallet with the given id or key.
+// An identity without wallets in its allow-list can access every wallet.
+func (i Identity) CanAccessWallet(ids ...string) bool {
+	if len(i.Wallets) == 0 {
+		return true
+	}
+	for _, allowed := range i.Wallets {
+		for _, id := range ids {
+			if id != "" && allowed == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CertIdentities maps the subjects of the client certificates to the identity of the services presenting them.
+type CertIdentities map[string]Identity
+
+// Lookup returns the identity of the subject of cert, matched either by its common name or by its
+// full distinguished name.
+func (ci CertIdentities) Lookup(cert *x509.Certificate) (Identity, bool) {
+	if identity, ok := ci[cert.Subject.CommonName]; ok {
+		return identity, true
+	}
+	identity, ok := ci[cert.Subject.String()]
+	return identity, ok
+}
+
+// ValidScope reports whether scope is one of the known scopes.
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateKey returns a new random api key.
+func GenerateKey() (string, error) {
+	bytes := make([]byte, 24)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return KeyPrefix + hex.EncodeToString(bytes), nil
+}
+
+// GenerateID returns a new random identifier for an api key.
+func GenerateID() (string, error) {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return "key_" + hex.EncodeToString(bytes), nil
+}
+
+// HashKey returns the hash stored in place of the api key.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(key)))
+	return hex.EncodeToString(sum[:])
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the identity.
+func NewContext(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// FromContext returns the identity stored in ctx, if any.
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(contextKey{}).(Identity)
+	return identity, ok
+}
@@ -1,16 +1,28 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
-	"github.com/fsnotify/fsnotify"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/redact"
+	"github.com/mitchellh/mapstructure"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
+// ClientIdentity grants scopes and wallets to the services presenting a client certificate
+// whose common name or distinguished name is Subject.
+type ClientIdentity struct {
+	Subject string   `mapstructure:"subject" json:"subject"`
+	Scopes  []string `mapstructure:"scopes" json:"scopes"`
+	Wallets []string `mapstructure:"wallets" json:"wallets"`
+}
+
 // Configuration estructura
 type Configuration struct {
 	APIRestServerHost string `mapstructure:"api_host"`
@@ -18,12 +30,104 @@ type Configuration struct {
 	LoggingPath       string `mapstructure:"api_logpath"`
 	LoggingFile       string `mapstructure:"api_logfile"`
 	LoggingLevel      string `mapstructure:"api_loglevel"`
+	// TrustedProxies are the addresses or CIDRs of the proxies whose X-Forwarded-For and X-Real-IP headers
+	// give the client ip, none by default: the client ip is the remote address
+	TrustedProxies []string `mapstructure:"api_trusted_proxies"`
+
+	// LoggingFormat is either text or json
+	LoggingFormat string   `mapstructure:"api_log_format"`
+	LoggingSinks  []string `mapstructure:"api_log_sinks"`
+	// the log file rotates when it grows over LoggingMaxSizeMB or every LoggingRotateInterval, 0 disables either
+	LoggingMaxSizeMB      int           `mapstructure:"api_log_max_size_mb"`
+	LoggingRotateInterval time.Duration `mapstructure:"api_log_rotate_interval"`
+	LoggingMaxBackups     int           `mapstructure:"api_log_max_backups"`
+	LoggingMaxAge         time.Duration `mapstructure:"api_log_max_age"`
+	// LoggingSyslogNetwork is either udp, tcp, unix or unixgram
+	LoggingSyslogNetwork string `mapstructure:"api_log_syslog_network"`
+	LoggingSyslogAddr    string `mapstructure:"api_log_syslog_addr"`
+	LoggingSyslogTag     string `mapstructure:"api_log_syslog_tag"`
+	// LoggingComponentLevels override api_loglevel for some components: http, lnpay and events
+	LoggingComponentLevels map[string]string `mapstructure:"api_log_component_levels"`
+	// AccessLogReadSampleRate is the fraction of the successful reads in the access log, 1 logs them all.
+	// The other requests, moving money included, are always logged
+	AccessLogReadSampleRate float64 `mapstructure:"api_access_log_read_sample_rate"`
+
+	// RedactFields are the fields masked in the logs, errors and traces besides the api keys and preimages,
+	// like the sensitive passThru fields of the invoices
+	RedactFields []string `mapstructure:"redact_fields"`
+
+	// ErrorFormat is either legacy or problem (RFC 7807)
+	ErrorFormat   string `mapstructure:"api_error_format"`
+	ErrorTypeBase string `mapstructure:"api_error_type_base"`
+
+	ReadTimeout time.Duration `mapstructure:"api_read_timeout"`
+	// WriteTimeout must stay disabled (0) to serve the invoice events streams
+	WriteTimeout time.Duration `mapstructure:"api_write_timeout"`
+	IdleTimeout  time.Duration `mapstructure:"api_idle_timeout"`
+	// ShutdownTimeout bounds how long a shutdown waits for the in-flight payments and connections
+	ShutdownTimeout time.Duration `mapstructure:"api_shutdown_timeout"`
+
+	// TLS is enabled when the certificate and key files are set, they are reloaded when the config file changes.
+	TLSCertFile string `mapstructure:"api_tls_cert_file"`
+	TLSKeyFile  string `mapstructure:"api_tls_key_file"`
+	// TLSClientAuth is either none, optional or require, verifying the client certificates against TLSClientCAFile
+	TLSClientAuth       string           `mapstructure:"api_tls_client_auth"`
+	TLSClientCAFile     string           `mapstructure:"api_tls_client_ca_file"`
+	TLSClientIdentities []ClientIdentity `mapstructure:"api_tls_client_identities"`
+
+	LNPay `mapstructure:",squash"`
+
+	// TracingExporter is either none or otlp, the spans are created anyway to correlate the logs
+	TracingExporter      string            `mapstructure:"tracing_exporter"`
+	TracingServiceName   string            `mapstructure:"tracing_service_name"`
+	TracingOTLPEndpoint  string            `mapstructure:"tracing_otlp_endpoint"`
+	TracingOTLPHeaders   map[string]string `mapstructure:"tracing_otlp_headers"`
+	TracingOTLPTimeout   time.Duration     `mapstructure:"tracing_otlp_timeout"`
+	TracingBatchSize     int               `mapstructure:"tracing_batch_size"`
+	TracingFlushInterval time.Duration     `mapstructure:"tracing_flush_interval"`
+	// TracingPropagateLNPay sends the traceparent header to lnpay, off to keep the trace ids internal
+	TracingPropagateLNPay bool `mapstructure:"tracing_propagate_lnpay"`
+
+	// HealthCacheTTL is how long the readiness probe reuses the result of the lnpay reachability check
+	HealthCacheTTL time.Duration `mapstructure:"health_cache_ttl"`
+	HealthTimeout  time.Duration `mapstructure:"health_timeout"`
+
+	Limits   `mapstructure:",squash"`
+	Webhooks `mapstructure:",squash"`
+	Storage  `mapstructure:",squash"`
+	Auth     `mapstructure:",squash"`
+
+	// RateLimitBackend is either memory or redis
+	RateLimitBackend     string        `mapstructure:"ratelimit_backend"`
+	RateLimitRedisAddr   string        `mapstructure:"ratelimit_redis_addr"`
+	RateLimitPeriod      time.Duration `mapstructure:"ratelimit_period"`
+	RateLimitReadPerKey  int           `mapstructure:"ratelimit_read_per_key"`
+	RateLimitReadPerIP   int           `mapstructure:"ratelimit_read_per_ip"`
+	RateLimitMoneyPerKey int           `mapstructure:"ratelimit_money_per_key"`
+	RateLimitMoneyPerIP  int           `mapstructure:"ratelimit_money_per_ip"`
+
+	// IdempotencyBackend is either memory or redis
+	IdempotencyBackend   string        `mapstructure:"idempotency_backend"`
+	IdempotencyRedisAddr string        `mapstructure:"idempotency_redis_addr"`
+	IdempotencyTTL       time.Duration `mapstructure:"idempotency_ttl"`
+	IdempotencyLockTTL   time.Duration `mapstructure:"idempotency_lock_ttl"`
+	IdempotencyWait      time.Duration `mapstructure:"idempotency_wait"`
 }
 
-// Config is package struct containing conf params
+// EnvPrefix prefixes the environment variables overriding the config file keys.
+const EnvPrefix = "LNPAY_WRAPPER"
+
+// profile is the config file of the GO_ENVIRONMENT, merged again on every reload.
+var profile string
+
+// Config is package struct containing conf params, as loaded at startup. Current returns the configuration
+// in use after the reloads.
 var ConfMap Configuration
 
-func Load(path string, name string, ext string) {
+// Load reads the configuration from the defaults, the config file path/name.ext, the profile of the
+// GO_ENVIRONMENT path/name.<environment>.ext and the LNPAY_WRAPPER_* environment variables, each one
+// overriding the previous ones. It returns a ValidationError listing all the problems found.
+func Load(path string, name string, ext string) error {
 
 	// name := "parameters"
 	// ext := "yml"
@@ -37,30 +141,203 @@ func Load(path string, name string, ext string) {
 	// API
 	viper.SetDefault("api_host", "127.0.0.1")
 	viper.SetDefault("api_port", ":8080")
+	viper.SetDefault("api_read_timeout", "15s")
+	viper.SetDefault("api_write_timeout", "0s")
+	viper.SetDefault("api_idle_timeout", "60s")
+	viper.SetDefault("api_shutdown_timeout", "30s")
+	viper.SetDefault("api_trusted_proxies", []string{})
+	// TLS
+	viper.SetDefault("api_tls_cert_file", "")
+	viper.SetDefault("api_tls_key_file", "")
+	viper.SetDefault("api_tls_client_auth", "none")
+	viper.SetDefault("api_tls_client_ca_file", "")
+	viper.SetDefault("api_tls_client_identities", []ClientIdentity{})
 	// LOG
 	viper.SetDefault("api_logpath", "/var/log/")
 	viper.SetDefault("api_logfile", "lnpay_wrapper_api_go.log")
 	viper.SetDefault("api_loglevel", "trace")
+	viper.SetDefault("api_log_format", "text")
+	viper.SetDefault("api_log_sinks", []string{"file"})
+	viper.SetDefault("api_log_max_size_mb", 100)
+	viper.SetDefault("api_log_rotate_interval", "24h")
+	viper.SetDefault("api_log_max_backups", 7)
+	viper.SetDefault("api_log_max_age", "168h")
+	viper.SetDefault("api_log_syslog_network", "udp")
+	viper.SetDefault("api_log_syslog_addr", "localhost:514")
+	viper.SetDefault("api_log_syslog_tag", "lnpay-wrapper-api-go")
+	viper.SetDefault("api_log_component_levels", map[string]string{})
+	viper.SetDefault("api_access_log_read_sample_rate", 1)
+	// REDACTION
+	viper.SetDefault("redact_fields", []string{})
+	// ERRORS
+	viper.SetDefault("api_error_format", "legacy")
+	viper.SetDefault("api_error_type_base", "https://lnpay-wrapper-api-go/errors/")
+	// LNPAY
+	viper.SetDefault("lnpay_api_key", "")
+	viper.SetDefault("lnpay_api_key_ref", "")
+	viper.SetDefault("lnpay_base_url", "https://api.lnpay.co/v1")
+	viper.SetDefault("lnpay_node", "default")
+	viper.SetDefault("lnpay_timeout", "30s")
+	viper.SetDefault("lnpay_retries", 2)
+	viper.SetDefault("lnpay_retry_backoff", "200ms")
+	viper.SetDefault("lnpay_breaker_threshold", 5)
+	viper.SetDefault("lnpay_breaker_cooldown", "30s")
+	// TRACING
+	viper.SetDefault("tracing_exporter", "none")
+	viper.SetDefault("tracing_service_name", "lnpay-wrapper-api-go")
+	viper.SetDefault("tracing_otlp_endpoint", "http://localhost:4318/v1/traces")
+	viper.SetDefault("tracing_otlp_headers", map[string]string{})
+	viper.SetDefault("tracing_otlp_timeout", "10s")
+	viper.SetDefault("tracing_batch_size", 512)
+	viper.SetDefault("tracing_flush_interval", "5s")
+	viper.SetDefault("tracing_propagate_lnpay", false)
+	// HEALTH
+	viper.SetDefault("health_cache_ttl", "30s")
+	viper.SetDefault("health_timeout", "5s")
+	// LIMITS
+	viper.SetDefault("limits_min_amount_sats", 1)
+	viper.SetDefault("limits_max_amount_sats", 10000000)
+	// EVENTS
+	viper.SetDefault("events_poll_interval", "5s")
+	viper.SetDefault("events_allowed_origins", []string{})
+	viper.SetDefault("events_token_secret", "")
+	viper.SetDefault("events_token_secret_ref", "")
+	viper.SetDefault("events_token_ttl", "1h")
+	viper.SetDefault("events_max_subscriptions", 1000)
+	viper.SetDefault("webhook_queue_size", 100)
+	viper.SetDefault("webhook_workers", 2)
+	// STORAGE
+	viper.SetDefault("storage_backend", "memory")
+	// AUTH
+	viper.SetDefault("auth_bootstrap_key", "")
+	viper.SetDefault("auth_bootstrap_key_ref", "")
+	// RATE LIMIT
+	viper.SetDefault("ratelimit_backend", "memory")
+	viper.SetDefault("ratelimit_redis_addr", "localhost:6379")
+	viper.SetDefault("ratelimit_period", "1m")
+	viper.SetDefault("ratelimit_read_per_key", 300)
+	viper.SetDefault("ratelimit_read_per_ip", 600)
+	viper.SetDefault("ratelimit_money_per_key", 30)
+	viper.SetDefault("ratelimit_money_per_ip", 60)
+	// IDEMPOTENCY
+	viper.SetDefault("idempotency_backend", "memory")
+	viper.SetDefault("idempotency_redis_addr", "localhost:6379")
+	viper.SetDefault("idempotency_ttl", "24h")
+	viper.SetDefault("idempotency_lock_ttl", "1m")
+	viper.SetDefault("idempotency_wait", "5s")
 
+	// LNPAY_WRAPPER_API_PORT overrides api_port
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.AutomaticEnv()
+
+	var problems []string
 	if _, err := os.Stat(filepath.Join(path, name+"."+ext)); err == nil {
-		err = viper.ReadInConfig()
-		if err == nil {
-			viper.WatchConfig()
-			viper.OnConfigChange(func(e fsnotify.Event) {
-				// TODO: load new config values ...
-				log.Println("Config file changed: ", e.Name)
-			})
-		} else {
-			log.Errorln(err)
+		if err = viper.ReadInConfig(); err != nil {
+			problems = append(problems, err.Error())
 		}
 	} else {
 		log.Warningf("File parameters.yml not found. Working with default config: %s \n", err)
 	}
 
-	err := viper.Unmarshal(&ConfMap)
-	if err != nil {
-		log.Fatalf("Unable to decode into struct, %+v", err)
+	if environment := os.Getenv("GO_ENVIRONMENT"); environment != "" {
+		profile = filepath.Join(path, name+"."+environment+"."+ext)
+		if err := mergeProfile(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	conf, decodeProblems := decode()
+	problems = append(problems, decodeProblems...)
+	ConfMap = conf
+	current.Store(ConfMap)
+	if file := viper.ConfigFileUsed(); file != "" && len(problems) == 0 {
+		if err := watch(file); err != nil {
+			log.Warningf("Config file %s not watched, the changes apply on restart: %s \n", file, err)
+		}
 	}
 	fmt.Printf("Load configuration : \n")
-	spew.Dump(ConfMap)
+	fmt.Print(redact.String(spew.Sdump(ConfMap.Redacted())))
+
+	if err := ConfMap.Validate(); err != nil {
+		var invalid ValidationError
+		if errors.As(err, &invalid) {
+			problems = append(problems, invalid.Problems...)
+		}
+	}
+	if len(problems) > 0 {
+		return ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// mergeProfile merges the profile of the environment, when it has one, over the config file.
+func mergeProfile() error {
+	if profile == "" {
+		return nil
+	}
+	file, err := os.Open(profile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := viper.MergeConfig(file); err != nil {
+		return fmt.Errorf("%s: %w", profile, err)
+	}
+	return nil
+}
+
+// decode builds a configuration from the config file, the profile and the environment, returning
+// every field that can't be decoded and every secret that can't be read as a problem.
+func decode() (Configuration, []string) {
+	var conf Configuration
+	var problems []string
+	if err := viper.Unmarshal(&conf); err != nil {
+		var decodeErr *mapstructure.Error
+		if errors.As(err, &decodeErr) {
+			problems = append(problems, decodeErr.Errors...)
+		} else {
+			problems = append(problems, err.Error())
+		}
+	}
+	return conf, append(problems, conf.resolveSecrets()...)
+}
+
+// Redacted returns a copy of the configuration with the secrets masked, to be printed.
+func (c Configuration) Redacted() Configuration {
+	c.LNPayAPIKey = redact.Secret(c.LNPayAPIKey)
+	c.AuthBootstrapKey = redact.Secret(c.AuthBootstrapKey)
+	c.EventsTokenSecret = redact.Secret(c.EventsTokenSecret)
+	headers := make(map[string]string, len(c.TracingOTLPHeaders))
+	for name, value := range c.TracingOTLPHeaders {
+		headers[name] = redact.Secret(value)
+	}
+	c.TracingOTLPHeaders = headers
+	return c
+}
+
+// Values returns the configuration keyed by the names of the config file, with the durations
+// written as in the file. Call it on the Redacted configuration to expose it.
+func (c Configuration) Values() map[string]interface{} {
+	values := make(map[string]interface{})
+	var collect func(v reflect.Value)
+	collect = func(v reflect.Value) {
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.Anonymous {
+				// a section, its keys are at the top level
+				collect(v.Field(i))
+				continue
+			}
+			value := v.Field(i).Interface()
+			if d, ok := value.(time.Duration); ok {
+				value = d.String()
+			}
+			values[field.Tag.Get("mapstructure")] = value
+		}
+	}
+	collect(reflect.ValueOf(c))
+	return values
 }
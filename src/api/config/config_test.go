@@ -0,0 +1,180 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+)
+
+// TestMain loads the defaults, without a config file so nothing is watched, and the settings of the environment.
+func TestMain(m *testing.M) {
+	logger.InitLog(logger.ConfigureLog{LoggingLevel: "panic", Format: logger.FormatText, Sinks: []string{"stderr"}})
+	os.Setenv(EnvPrefix+"_LNPAY_API_KEY", "sak_testkey")
+	if err := Load(os.TempDir()+"/lnpay-wrapper-config-test", "parameters", "yml"); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func problems(err error) []string {
+	var invalid ValidationError
+	if errors.As(err, &invalid) {
+		return invalid.Problems
+	}
+	return nil
+}
+
+func TestDefaultsAreValid(t *testing.T) {
+	if err := ConfMap.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *Configuration)
+		problem string
+	}{
+		{"no api key", func(c *Configuration) { c.LNPayAPIKey = "" }, "lnpay_api_key or lnpay_api_key_ref is required"},
+		{"level", func(c *Configuration) { c.LoggingLevel = "loud" }, `api_loglevel must be a valid level, got "loud"`},
+		{"component level", func(c *Configuration) { c.LoggingComponentLevels = map[string]string{"http": "loud"} },
+			`api_log_component_levels.http must be a valid level, got "loud"`},
+		{"file sink", func(c *Configuration) { c.LoggingSinks = []string{"file"}; c.LoggingFile = "" }, "api_logfile is required by the file log sink"},
+		{"proxy", func(c *Configuration) { c.TrustedProxies = []string{"10.0.0.0/33"} },
+			`api_trusted_proxies must be ip addresses or CIDRs, got "10.0.0.0/33"`},
+		{"tls pair", func(c *Configuration) { c.TLSCertFile = "cert.pem" }, "api_tls_cert_file and api_tls_key_file must be set together"},
+		{"lnpay url", func(c *Configuration) { c.LNPayBaseURL = "api.lnpay.co" }, `lnpay_base_url must be an http or https url, got "api.lnpay.co"`},
+		{"memory exporter", func(c *Configuration) { c.TracingExporter = "memory" }, `tracing_exporter must be none or otlp, got "memory"`},
+		{"min amount", func(c *Configuration) { c.LimitsMinAmountSats = 0 }, "limits_min_amount_sats must be positive"},
+		{"max amount", func(c *Configuration) { c.LimitsMaxAmountSats = c.LimitsMinAmountSats - 1 },
+			"limits_max_amount_sats must be greater or equal than limits_min_amount_sats"},
+		{"token ttl", func(c *Configuration) { c.EventsTokenTTL = 0 }, "events_token_ttl must be positive"},
+		{"subscriptions", func(c *Configuration) { c.EventsMaxSubscriptions = 0 }, "events_max_subscriptions must be positive"},
+		{"redis addr", func(c *Configuration) { c.RateLimitBackend = "redis"; c.RateLimitRedisAddr = "" },
+			"ratelimit_redis_addr is required by the redis backend"},
+		{"negative budget", func(c *Configuration) { c.RateLimitMoneyPerIP = -1 }, "the rate limits can't be negative"},
+	}
+	for _, tt := range tests {
+		conf := ConfMap
+		tt.change(&conf)
+		got := problems(conf.Validate())
+		if len(got) != 1 || got[0] != tt.problem {
+			t.Errorf("%s: got problems %q, want %q", tt.name, got, tt.problem)
+		}
+	}
+
+	conf := ConfMap
+	conf.LNPayAPIKey, conf.WebhookWorkers = "", 0
+	if got := problems(conf.Validate()); len(got) != 2 {
+		t.Errorf("got problems %q, want every problem listed", got)
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	t.Setenv("TEST_BOOTSTRAP_KEY", "sak_bootstrap")
+	tests := []struct {
+		name    string
+		conf    Configuration
+		want    string
+		problem string
+	}{
+		{"env ref", Configuration{Auth: Auth{AuthBootstrapKeyRef: "env:TEST_BOOTSTRAP_KEY"}}, "sak_bootstrap", ""},
+		{"value", Configuration{Auth: Auth{AuthBootstrapKey: "sak_value"}}, "sak_value", ""},
+		{"both", Configuration{Auth: Auth{AuthBootstrapKey: "sak_value", AuthBootstrapKeyRef: "env:TEST_BOOTSTRAP_KEY"}},
+			"sak_value", "set either auth_bootstrap_key or auth_bootstrap_key_ref"},
+		{"unset env", Configuration{Auth: Auth{AuthBootstrapKeyRef: "env:TEST_UNSET_KEY"}},
+			"", "auth_bootstrap_key_ref: environment variable TEST_UNSET_KEY is not set"},
+		{"bad ref", Configuration{Auth: Auth{AuthBootstrapKeyRef: "vault:key"}},
+			"", `auth_bootstrap_key_ref: "vault:key" must be env:VARIABLE or file:/path`},
+	}
+	for _, tt := range tests {
+		got := tt.conf.resolveSecrets()
+		if tt.conf.AuthBootstrapKey != tt.want {
+			t.Errorf("%s: key %q, want %q", tt.name, tt.conf.AuthBootstrapKey, tt.want)
+		}
+		if (tt.problem == "" && len(got) != 0) || (tt.problem != "" && (len(got) != 1 || got[0] != tt.problem)) {
+			t.Errorf("%s: got problems %q, want %q", tt.name, got, tt.problem)
+		}
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	next := ConfMap
+	next.LimitsMaxAmountSats++
+	next.TrustedProxies = []string{"10.0.0.1"}
+	next.LoggingComponentLevels = map[string]string{"http": "debug"}
+	want := []string{"api_trusted_proxies", "api_log_component_levels", "limits_max_amount_sats"}
+	if got := changedFields(ConfMap, next); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := changedFields(ConfMap, ConfMap); len(got) != 0 {
+		t.Errorf("got %q, want nothing changed", got)
+	}
+}
+
+func TestReload(t *testing.T) {
+	var applied []int64
+	Subscribe("limits", []string{"limits_min_amount_sats", "limits_max_amount_sats"}, func(conf Configuration) error {
+		applied = append(applied, conf.LimitsMaxAmountSats)
+		return nil
+	})
+	failing := false
+	Subscribe("failing", []string{"limits_max_amount_sats"}, func(conf Configuration) error {
+		if failing {
+			return errors.New("can't apply")
+		}
+		return nil
+	})
+	defer func() { subscribers = nil }()
+	startup := ConfMap.LimitsMaxAmountSats
+
+	t.Setenv(EnvPrefix+"_LIMITS_MAX_AMOUNT_SATS", "5000")
+	changed, err := Reload()
+	if err != nil || !reflect.DeepEqual(changed, []string{"limits_max_amount_sats"}) {
+		t.Fatalf("got %q %v, want the max amount changed", changed, err)
+	}
+	if Current().LimitsMaxAmountSats != 5000 || ConfMap.LimitsMaxAmountSats != startup {
+		t.Errorf("current max %d and startup max %d, want 5000 and %d", Current().LimitsMaxAmountSats, ConfMap.LimitsMaxAmountSats, startup)
+	}
+	if changed, err := Reload(); err != nil || len(changed) != 0 {
+		t.Errorf("reload without changes got %q %v", changed, err)
+	}
+
+	tests := []struct {
+		name    string
+		env     string
+		value   string
+		failing bool
+		problem string
+	}{
+		{"restart only key", "_API_PORT", ":9999", false, "api_port can only change on restart"},
+		{"invalid value", "_LIMITS_MIN_AMOUNT_SATS", "0", false, "limits_min_amount_sats must be positive"},
+		{"failing subscriber", "_LIMITS_MAX_AMOUNT_SATS", "6000", true, ""},
+	}
+	for _, tt := range tests {
+		applied = nil
+		failing = tt.failing
+		t.Setenv(EnvPrefix+tt.env, tt.value)
+		_, err := Reload()
+		if err == nil {
+			t.Fatalf("%s: reload applied", tt.name)
+		}
+		if tt.problem != "" {
+			if got := problems(err); len(got) != 1 || got[0] != tt.problem {
+				t.Errorf("%s: got %v, want %q", tt.name, err, tt.problem)
+			}
+		}
+		if tt.failing && !reflect.DeepEqual(applied, []int64{6000, 5000}) {
+			t.Errorf("%s: applied %v, want the new max rolled back to the current one", tt.name, applied)
+		}
+		if Current().LimitsMaxAmountSats != 5000 || Current().APIRestServerPort == ":9999" {
+			t.Errorf("%s: the current configuration changed", tt.name)
+		}
+		os.Unsetenv(EnvPrefix + tt.env)
+	}
+	failing = false
+}
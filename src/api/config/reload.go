@@ -0,0 +1,211 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+	"github.com/spf13/viper"
+)
+
+var (
+	// current holds the Configuration in use, swapped when a reload is applied.
+	current atomic.Value
+	// reloadMu serializes the reloads, both the ones of the file watcher and the ones asked through the api,
+	// and every use of viper after Load.
+	reloadMu    sync.Mutex
+	subscribers []subscriber
+)
+
+type subscriber struct {
+	name  string
+	keys  []string
+	apply func(Configuration) error
+}
+
+// Subscribe registers apply to be called with every reloaded configuration, in the order of subscription.
+// keys are the keys of the config file apply takes into account: a reload changing any key no subscriber
+// takes is rejected, since it would only have effect on restart.
+// When apply fails the reload is rolled back: the subscribers already called are given back the previous configuration.
+func Subscribe(name string, keys []string, apply func(Configuration) error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	subscribers = append(subscribers, subscriber{name: name, keys: keys, apply: apply})
+}
+
+// Current returns the configuration in use. It's ConfMap until a reload is applied, only the keys
+// taken by the subscribers may differ from ConfMap.
+func Current() Configuration {
+	if conf, ok := current.Load().(Configuration); ok {
+		return conf
+	}
+	return ConfMap
+}
+
+// Reload reads the config file, merged with the profile and the environment, into a new configuration,
+// validates it and applies it to the subscribers before swapping it in. Invalid configurations and changes
+// to the keys read on restart only are rejected, failed reloads are rolled back, the current configuration
+// is kept in both cases. Every reload is logged for auditing.
+// It returns the keys changed, a ValidationError when the configuration is rejected or the error of the
+// subscriber that made it roll back.
+func Reload() ([]string, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	file := viper.ConfigFileUsed()
+	if file != "" {
+		if err := viper.ReadInConfig(); err != nil {
+			logger.Error("Configuration reload rejected, the file can't be read", err, "file:"+file)
+			return nil, ValidationError{Problems: []string{err.Error()}}
+		}
+	}
+	if err := mergeProfile(); err != nil {
+		logger.Error("Configuration reload rejected, the profile can't be read", err, "file:"+file)
+		return nil, ValidationError{Problems: []string{err.Error()}}
+	}
+	next, problems := decode()
+	if len(problems) > 0 {
+		err := ValidationError{Problems: problems}
+		logger.Error("Configuration reload rejected, the file can't be decoded", err, "file:"+file)
+		return nil, err
+	}
+	previous := Current()
+	changed := changedFields(previous, next)
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	tags := []string{"file:" + file, "changed:" + strings.Join(changed, ",")}
+	if err := next.Validate(); err != nil {
+		logger.Error("Configuration reload rejected", err, tags...)
+		return nil, err
+	}
+	if err := restartOnly(changed); err != nil {
+		logger.Error("Configuration reload rejected", err, tags...)
+		return nil, err
+	}
+	if err := apply(previous, next); err != nil {
+		logger.Error("Configuration reload rolled back", err, tags...)
+		return nil, err
+	}
+	current.Store(next)
+	logger.Warn("Configuration reloaded", tags...)
+	return changed, nil
+}
+
+// restartOnly returns a ValidationError listing the changed keys no subscriber takes.
+func restartOnly(changed []string) error {
+	taken := make(map[string]bool)
+	for _, s := range subscribers {
+		for _, key := range s.keys {
+			taken[key] = true
+		}
+	}
+	var problems []string
+	for _, key := range changed {
+		if !taken[key] {
+			problems = append(problems, key+" can only change on restart")
+		}
+	}
+	if len(problems) > 0 {
+		return ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// watch reloads the configuration when the config file or the profile changes, including when they are
+// replaced through a symlink as in the kubernetes config maps. It's used instead of viper.WatchConfig,
+// which reads the file from its own goroutine, so that every read goes through Reload.
+func watch(file string) error {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	watched := map[string]bool{file: true}
+	if profile != "" {
+		if abs, err := filepath.Abs(profile); err == nil {
+			watched[abs] = true
+		}
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	targets := make(map[string]string)
+	for name := range watched {
+		if err := watcher.Add(filepath.Dir(name)); err != nil {
+			watcher.Close()
+			return err
+		}
+		targets[name], _ = filepath.EvalSymlinks(name)
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				changed := watched[filepath.Clean(event.Name)] && event.Op&(fsnotify.Write|fsnotify.Create) != 0
+				for name, target := range targets {
+					if now, _ := filepath.EvalSymlinks(name); now != "" && now != target {
+						targets[name] = now
+						changed = true
+					}
+				}
+				if changed {
+					Reload()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Error("Error watching the config file", err, "file:"+file)
+			}
+		}
+	}()
+	return nil
+}
+
+func apply(previous Configuration, next Configuration) error {
+	for i, s := range subscribers {
+		err := s.apply(next)
+		if err == nil {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			if rollbackErr := subscribers[j].apply(previous); rollbackErr != nil {
+				logger.Error("Error rolling back the configuration of "+subscribers[j].name, rollbackErr)
+			}
+		}
+		return fmt.Errorf("%s: %w", s.name, err)
+	}
+	return nil
+}
+
+// changedFields returns the keys of the config file whose values differ, the values aren't returned
+// since they may be secrets.
+func changedFields(previous Configuration, next Configuration) []string {
+	var changed []string
+	var compare func(prev reflect.Value, nxt reflect.Value)
+	compare = func(prev reflect.Value, nxt reflect.Value) {
+		for i := 0; i < prev.NumField(); i++ {
+			field := prev.Type().Field(i)
+			if field.Anonymous {
+				// a section, its keys are at the top level
+				compare(prev.Field(i), nxt.Field(i))
+				continue
+			}
+			if !reflect.DeepEqual(prev.Field(i).Interface(), nxt.Field(i).Interface()) {
+				changed = append(changed, field.Tag.Get("mapstructure"))
+			}
+		}
+	}
+	compare(reflect.ValueOf(previous), reflect.ValueOf(next))
+	return changed
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// resolveSecrets reads the secrets given by reference, returning the problems found.
+func (c *Configuration) resolveSecrets() []string {
+	var problems []string
+	resolve := func(value *string, ref string, key string) {
+		if ref == "" {
+			return
+		}
+		if *value != "" {
+			problems = append(problems, fmt.Sprintf("set either %s or %s_ref", key, key))
+			return
+		}
+		secret, err := readSecret(ref)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s_ref: %v", key, err))
+			return
+		}
+		*value = secret
+	}
+	resolve(&c.LNPayAPIKey, c.LNPayAPIKeyRef, "lnpay_api_key")
+	resolve(&c.AuthBootstrapKey, c.AuthBootstrapKeyRef, "auth_bootstrap_key")
+	resolve(&c.EventsTokenSecret, c.EventsTokenSecretRef, "events_token_secret")
+	return problems
+}
+
+// readSecret reads the secret referenced by env:VARIABLE or file:/path.
+func readSecret(ref string) (string, error) {
+	parts := strings.SplitN(ref, ":", 2)
+	if len(parts) < 2 || parts[1] == "" {
+		return "", fmt.Errorf("%q must be env:VARIABLE or file:/path", ref)
+	}
+	kind, name := parts[0], parts[1]
+	switch kind {
+	case "env":
+		secret, ok := os.LookupEnv(name)
+		if !ok || secret == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	case "file":
+		secret, err := os.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(secret)), nil
+	}
+	return "", fmt.Errorf("%q must be env:VARIABLE or file:/path", ref)
+}
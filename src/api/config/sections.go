@@ -0,0 +1,59 @@
+package config
+
+import "time"
+
+// The sections group the settings of a concern. They are embedded in Configuration, so their keys
+// stay at the top level of the config file and their fields are promoted.
+
+// LNPay sets how the lnpay api is called.
+type LNPay struct {
+	LNPayAPIKey string `mapstructure:"lnpay_api_key"`
+	// LNPayAPIKeyRef reads the api key from env:VARIABLE or file:/path instead of the config file
+	LNPayAPIKeyRef string `mapstructure:"lnpay_api_key_ref"`
+	LNPayBaseURL   string `mapstructure:"lnpay_base_url"`
+	// LNPayNode is the node queried for routes and invoices, the default node of the account by default
+	LNPayNode    string        `mapstructure:"lnpay_node"`
+	LNPayTimeout time.Duration `mapstructure:"lnpay_timeout"`
+	// the calls reading from lnpay are retried LNPayRetries times, the calls moving funds never are
+	LNPayRetries      int           `mapstructure:"lnpay_retries"`
+	LNPayRetryBackoff time.Duration `mapstructure:"lnpay_retry_backoff"`
+	// the circuit opens after LNPayBreakerThreshold consecutive failures, 0 disables it
+	LNPayBreakerThreshold int           `mapstructure:"lnpay_breaker_threshold"`
+	LNPayBreakerCooldown  time.Duration `mapstructure:"lnpay_breaker_cooldown"`
+}
+
+// Limits bounds the amounts accepted by the api.
+type Limits struct {
+	LimitsMinAmountSats int64 `mapstructure:"limits_min_amount_sats"`
+	LimitsMaxAmountSats int64 `mapstructure:"limits_max_amount_sats"`
+}
+
+// Webhooks sets how the invoice events are received from lnpay and streamed to the clients.
+type Webhooks struct {
+	EventsPollInterval   time.Duration `mapstructure:"events_poll_interval"`
+	EventsAllowedOrigins []string      `mapstructure:"events_allowed_origins"`
+	// EventsTokenSecret signs the tokens CreateInvoice hands out to follow the invoice events.
+	// A random one is generated when empty, then the tokens are only valid on this instance until restart.
+	EventsTokenSecret string `mapstructure:"events_token_secret"`
+	// EventsTokenSecretRef reads the token secret from env:VARIABLE or file:/path instead of the config file
+	EventsTokenSecretRef string        `mapstructure:"events_token_secret_ref"`
+	EventsTokenTTL       time.Duration `mapstructure:"events_token_ttl"`
+	// EventsMaxSubscriptions caps the event streams open at once
+	EventsMaxSubscriptions int `mapstructure:"events_max_subscriptions"`
+	WebhookQueueSize       int `mapstructure:"webhook_queue_size"`
+	WebhookWorkers         int `mapstructure:"webhook_workers"`
+}
+
+// Storage sets where the wallets and the api keys are kept.
+type Storage struct {
+	// StorageBackend is memory, the only backend for now
+	StorageBackend string `mapstructure:"storage_backend"`
+}
+
+// Auth sets how the api keys are bootstrapped.
+type Auth struct {
+	// AuthBootstrapKey is an admin api key accepted from startup, used to issue the other keys.
+	AuthBootstrapKey string `mapstructure:"auth_bootstrap_key"`
+	// AuthBootstrapKeyRef reads the bootstrap key from env:VARIABLE or file:/path instead of the config file
+	AuthBootstrapKeyRef string `mapstructure:"auth_bootstrap_key_ref"`
+}
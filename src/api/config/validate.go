@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ValidationError lists every problem found in a configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Details lists the problems one per line, to be read by whoever fixes the configuration.
+func (e ValidationError) Details() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks the configuration, returning a ValidationError with all the problems found.
+func (c Configuration) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.APIRestServerPort != "", "api_port is required")
+	check(c.ReadTimeout >= 0, "api_read_timeout can't be negative")
+	check(c.WriteTimeout >= 0, "api_write_timeout can't be negative")
+	check(c.IdleTimeout >= 0, "api_idle_timeout can't be negative")
+	check(c.ShutdownTimeout > 0, "api_shutdown_timeout must be positive")
+	for _, proxy := range c.TrustedProxies {
+		check(validProxy(proxy), "api_trusted_proxies must be ip addresses or CIDRs, got %q", proxy)
+	}
+	_, err := log.ParseLevel(c.LoggingLevel)
+	check(err == nil, "api_loglevel must be a valid level, got %q", c.LoggingLevel)
+	components := make([]string, 0, len(c.LoggingComponentLevels))
+	for component := range c.LoggingComponentLevels {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	for _, component := range components {
+		_, err := log.ParseLevel(c.LoggingComponentLevels[component])
+		check(err == nil, "api_log_component_levels.%s must be a valid level, got %q", component, c.LoggingComponentLevels[component])
+	}
+	check(oneOf(c.LoggingFormat, "text", "json"), "api_log_format must be text or json, got %q", c.LoggingFormat)
+	for _, sink := range c.LoggingSinks {
+		check(oneOf(sink, "stderr", "file", "syslog"), "api_log_sinks must be stderr, file or syslog, got %q", sink)
+		check(sink != "file" || c.LoggingFile != "", "api_logfile is required by the file log sink")
+		check(sink != "syslog" || c.LoggingSyslogAddr != "", "api_log_syslog_addr is required by the syslog log sink")
+	}
+	check(c.LoggingMaxSizeMB >= 0 && c.LoggingMaxBackups >= 0, "api_log_max_size_mb and api_log_max_backups can't be negative")
+	check(c.LoggingRotateInterval >= 0 && c.LoggingMaxAge >= 0, "api_log_rotate_interval and api_log_max_age can't be negative")
+	check(oneOf(c.LoggingSyslogNetwork, "udp", "tcp", "unix", "unixgram"),
+		"api_log_syslog_network must be udp, tcp, unix or unixgram, got %q", c.LoggingSyslogNetwork)
+	check(c.AccessLogReadSampleRate >= 0 && c.AccessLogReadSampleRate <= 1,
+		"api_access_log_read_sample_rate must be between 0 and 1, got %v", c.AccessLogReadSampleRate)
+	check(oneOf(c.ErrorFormat, "legacy", "problem"), "api_error_format must be legacy or problem, got %q", c.ErrorFormat)
+
+	check((c.TLSCertFile == "") == (c.TLSKeyFile == ""), "api_tls_cert_file and api_tls_key_file must be set together")
+	check(oneOf(c.TLSClientAuth, "", "none", "optional", "require"), "api_tls_client_auth must be none, optional or require, got %q", c.TLSClientAuth)
+	check(oneOf(c.TLSClientAuth, "", "none") || c.TLSClientCAFile != "", "api_tls_client_ca_file is required to verify client certificates")
+	for i, client := range c.TLSClientIdentities {
+		check(client.Subject != "", "api_tls_client_identities[%d].subject is required", i)
+	}
+
+	check(c.LNPayAPIKey != "", "lnpay_api_key or lnpay_api_key_ref is required")
+	check(validURL(c.LNPayBaseURL), "lnpay_base_url must be an http or https url, got %q", c.LNPayBaseURL)
+	check(c.LNPayNode != "", "lnpay_node is required")
+	check(c.LNPayTimeout >= 0, "lnpay_timeout can't be negative")
+	check(c.LNPayRetries >= 0, "lnpay_retries can't be negative")
+	check(c.LNPayRetries == 0 || c.LNPayRetryBackoff >= 0, "lnpay_retry_backoff can't be negative")
+	check(c.LNPayBreakerThreshold >= 0, "lnpay_breaker_threshold can't be negative")
+	check(c.LNPayBreakerThreshold == 0 || c.LNPayBreakerCooldown > 0, "lnpay_breaker_cooldown must be positive")
+	check(oneOf(c.TracingExporter, "none", "otlp"), "tracing_exporter must be none or otlp, got %q", c.TracingExporter)
+	check(c.TracingExporter != "otlp" || c.TracingOTLPEndpoint != "", "tracing_otlp_endpoint is required by the otlp exporter")
+	check(c.TracingExporter != "otlp" || (c.TracingBatchSize > 0 && c.TracingFlushInterval > 0),
+		"tracing_batch_size and tracing_flush_interval must be positive")
+	check(c.HealthCacheTTL >= 0, "health_cache_ttl can't be negative")
+	check(c.HealthTimeout > 0, "health_timeout must be positive")
+
+	check(c.LimitsMinAmountSats > 0, "limits_min_amount_sats must be positive")
+	check(c.LimitsMaxAmountSats >= c.LimitsMinAmountSats, "limits_max_amount_sats must be greater or equal than limits_min_amount_sats")
+
+	check(c.EventsPollInterval > 0, "events_poll_interval must be positive")
+	check(c.EventsTokenTTL > 0, "events_token_ttl must be positive")
+	check(c.EventsMaxSubscriptions > 0, "events_max_subscriptions must be positive")
+	check(c.WebhookQueueSize > 0, "webhook_queue_size must be positive")
+	check(c.WebhookWorkers > 0, "webhook_workers must be positive")
+	check(oneOf(c.StorageBackend, "memory"), "storage_backend must be memory, got %q", c.StorageBackend)
+
+	check(oneOf(c.RateLimitBackend, "memory", "redis"), "ratelimit_backend must be memory or redis, got %q", c.RateLimitBackend)
+	check(c.RateLimitBackend != "redis" || c.RateLimitRedisAddr != "", "ratelimit_redis_addr is required by the redis backend")
+	check(c.RateLimitPeriod > 0, "ratelimit_period must be positive")
+	check(c.RateLimitReadPerKey >= 0 && c.RateLimitReadPerIP >= 0 &&
+		c.RateLimitMoneyPerKey >= 0 && c.RateLimitMoneyPerIP >= 0, "the rate limits can't be negative")
+
+	check(oneOf(c.IdempotencyBackend, "memory", "redis"), "idempotency_backend must be memory or redis, got %q", c.IdempotencyBackend)
+	check(c.IdempotencyBackend != "redis" || c.IdempotencyRedisAddr != "", "idempotency_redis_addr is required by the redis backend")
+	check(c.IdempotencyTTL > 0, "idempotency_ttl must be positive")
+	check(c.IdempotencyLockTTL > 0, "idempotency_lock_ttl must be positive")
+	check(c.IdempotencyWait >= 0, "idempotency_wait can't be negative")
+
+	if len(problems) > 0 {
+		return ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func validURL(rawurl string) bool {
+	u, err := url.Parse(rawurl)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func validProxy(proxy string) bool {
+	if _, _, err := net.ParseCIDR(proxy); err == nil {
+		return true
+	}
+	return net.ParseIP(proxy) != nil
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
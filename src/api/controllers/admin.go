@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/auth"
+	"github.com/lnpay-wrapper-api-go/src/api/storage"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/validation"
+)
+
+var apiKeyStore storage.APIKeyStore
+
+// ConfigureAuth sets the store used by the api keys administration controllers.
+func ConfigureAuth(keys storage.APIKeyStore) {
+	apiKeyStore = keys
+}
+
+type IssueAPIKeyRequest struct {
+	Name    string   `json:"name" binding:"required,max=64"`
+	Scopes  []string `json:"scopes" binding:"required,min=1,dive,oneof=read invoice pay admin"`
+	Wallets []string `json:"wallets" binding:"dive,required,walletkey"`
+}
+
+type APIKeyResponse struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Key       string     `json:"key,omitempty"`
+	Scopes    []string   `json:"scopes"`
+	Wallets   []string   `json:"wallets"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// IssueAPIKey is the handler to issue a new api key
+// @Summary Issue api key
+// @Description issues a new api key with the given scopes and wallet allow-list. The key is only returned once. A key restricted to some wallets can only issue keys restricted to those wallets.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param request body IssueAPIKeyRequest true "api key to issue"
+// @Success 201 {object} Envelope{data=APIKeyResponse}
+// @Failure 400 {object} apierrors.ApiError
+// @Failure 401 {object} apierrors.ApiError
+// @Failure 403 {object} apierrors.ApiError
+// @Failure 500 {object} apierrors.ApiError
+// @Router /admin/keys [post]
+func IssueAPIKey(c *gin.Context) {
+	var request IssueAPIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, validation.FromBindingError(err))
+		return
+	}
+
+	identity, _ := auth.FromContext(c.Request.Context())
+	if !canGrantWallets(identity, request.Wallets) {
+		respondError(c, apierrors.NewForbiddenApiError("The api key can only issue keys restricted to its own wallets"))
+		return
+	}
+
+	key, err := auth.GenerateKey()
+	if err != nil {
+		respondError(c, apierrors.NewInternalServerApiError("Error generating api key", err))
+		return
+	}
+	id, err := auth.GenerateID()
+	if err != nil {
+		respondError(c, apierrors.NewInternalServerApiError("Error generating api key", err))
+		return
+	}
+
+	apiKey := storage.APIKey{
+		ID:        id,
+		Name:      request.Name,
+		Hash:      auth.HashKey(key),
+		Scopes:    request.Scopes,
+		Wallets:   request.Wallets,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := apiKeyStore.Save(apiKey); err != nil {
+		respondError(c, apierrors.NewInternalServerApiError("Error saving api key", err))
+		return
+	}
+
+	response := newAPIKeyResponse(apiKey)
+	response.Key = key
+	respond(c, http.StatusCreated, response)
+}
+
+// ListAPIKeys is the handler to list the issued api keys
+// @Summary List api keys
+// @Description returns the issued api keys, without the keys themselves. A key restricted to some wallets only lists the keys restricted to those wallets.
+// @Tags admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} Envelope{data=[]APIKeyResponse}
+// @Failure 401 {object} apierrors.ApiError
+// @Failure 403 {object} apierrors.ApiError
+// @Failure 500 {object} apierrors.ApiError
+// @Router /admin/keys [get]
+func ListAPIKeys(c *gin.Context) {
+	keys, err := apiKeyStore.List()
+	if err != nil {
+		respondError(c, apierrors.NewInternalServerApiError("Error listing api keys", err))
+		return
+	}
+
+	identity, _ := auth.FromContext(c.Request.Context())
+	response := make([]APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		if canGrantWallets(identity, key.Wallets) {
+			response = append(response, newAPIKeyResponse(key))
+		}
+	}
+	respond(c, http.StatusOK, response)
+}
+
+// RevokeAPIKey is the handler to revoke an api key
+// @Summary Revoke api key
+// @Description revokes the api key with the given id. A key restricted to some wallets can only revoke keys restricted to those wallets.
+// @Tags admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "api key id"
+// @Success 200 {object} Envelope{data=APIKeyResponse}
+// @Failure 401 {object} apierrors.ApiError
+// @Failure 403 {object} apierrors.ApiError
+// @Failure 404 {object} apierrors.ApiError
+// @Failure 500 {object} apierrors.ApiError
+// @Router /admin/keys/{id} [delete]
+func RevokeAPIKey(c *gin.Context) {
+	id := c.Param("id")
+	key, err := apiKeyStore.Get(id)
+	if err == storage.ErrAPIKeyNotFound {
+		respondError(c, apierrors.NewNotFoundApiError("Api key "+id+" not found"))
+		return
+	}
+	if err != nil {
+		respondError(c, apierrors.NewInternalServerApiError("Error getting api key", err))
+		return
+	}
+
+	identity, _ := auth.FromContext(c.Request.Context())
+	if !canGrantWallets(identity, key.Wallets) {
+		respondError(c, apierrors.NewForbiddenApiError("The api key can only revoke keys restricted to its own wallets"))
+		return
+	}
+
+	if err := apiKeyStore.Revoke(id, time.Now().UTC()); err != nil {
+		respondError(c, apierrors.NewInternalServerApiError("Error revoking api key", err))
+		return
+	}
+	if key, err = apiKeyStore.Get(id); err != nil {
+		respondError(c, apierrors.NewInternalServerApiError("Error getting api key", err))
+		return
+	}
+	respond(c, http.StatusOK, newAPIKeyResponse(key))
+}
+
+func newAPIKeyResponse(key storage.APIKey) APIKeyResponse {
+	wallets := key.Wallets
+	if wallets == nil {
+		wallets = []string{}
+	}
+	return APIKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Scopes:    key.Scopes,
+		Wallets:   wallets,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
+
+// canGrantWallets reports whether identity may issue, list or revoke a key restricted to wallets: a key
+// restricted to some wallets can only manage keys restricted to those wallets.
+func canGrantWallets(identity auth.Identity, wallets []string) bool {
+	if len(identity.Wallets) == 0 {
+		return true
+	}
+	if len(wallets) == 0 {
+		return false
+	}
+	for _, wallet := range wallets {
+		walletId := ""
+		if record, found, err := walletStore.GetByKey(wallet); err == nil && found {
+			walletId = record.ID
+		}
+		if !identity.CanAccessWallet(wallet, walletId) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/auth"
+	"github.com/lnpay-wrapper-api-go/src/api/storage"
+)
+
+var (
+	unrestricted = auth.Identity{KeyID: "bootstrap", Scopes: []string{auth.ScopeAdmin}}
+	restricted   = auth.Identity{KeyID: "shop", Scopes: []string{auth.ScopeAdmin}, Wallets: []string{"wal_shop", "waki_shop"}}
+)
+
+// configureAdminKeys stores the bootstrap key, a key of the shop wallets and a key of another wallet.
+func configureAdminKeys(t *testing.T) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	walletStore = storage.NewMemoryWalletStore()
+	apiKeyStore = storage.NewMemoryAPIKeyStore()
+	now := time.Now().UTC()
+	for i, key := range []storage.APIKey{
+		{ID: "bootstrap", Scopes: []string{auth.ScopeAdmin}},
+		{ID: "shop-reader", Scopes: []string{auth.ScopeRead}, Wallets: []string{"waki_shop"}},
+		{ID: "other-reader", Scopes: []string{auth.ScopeRead}, Wallets: []string{"waki_other"}},
+	} {
+		key.CreatedAt = now.Add(time.Duration(i) * time.Second)
+		if err := apiKeyStore.Save(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func serveAdmin(identity auth.Identity, method string, target string, params gin.Params, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, nil)
+	c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), identity))
+	c.Params = params
+	handler(c)
+	return w
+}
+
+func TestListAPIKeys(t *testing.T) {
+	configureAdminKeys(t)
+	tests := []struct {
+		name     string
+		identity auth.Identity
+		want     []string
+	}{
+		{"unrestricted key", unrestricted, []string{"bootstrap", "shop-reader", "other-reader"}},
+		{"key restricted to some wallets", restricted, []string{"shop-reader"}},
+	}
+	for _, tt := range tests {
+		w := serveAdmin(tt.identity, http.MethodGet, "/admin/keys", nil, ListAPIKeys)
+		var body struct {
+			Data []APIKeyResponse `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("%s: got %d %s", tt.name, w.Code, w.Body.String())
+		}
+		var got []string
+		for _, key := range body.Data {
+			got = append(got, key.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: listed %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity auth.Identity
+		id       string
+		status   int
+	}{
+		{"own wallet key", restricted, "shop-reader", http.StatusOK},
+		{"other wallet key", restricted, "other-reader", http.StatusForbidden},
+		{"unrestricted key", restricted, "bootstrap", http.StatusForbidden},
+		{"unknown key", restricted, "missing", http.StatusNotFound},
+		{"any key by an unrestricted key", unrestricted, "other-reader", http.StatusOK},
+	}
+	for _, tt := range tests {
+		configureAdminKeys(t)
+		w := serveAdmin(tt.identity, http.MethodDelete, "/admin/keys/"+tt.id, gin.Params{{Key: "id", Value: tt.id}}, RevokeAPIKey)
+		if w.Code != tt.status {
+			t.Errorf("%s: got %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if key, err := apiKeyStore.Get(tt.id); err == nil && (key.RevokedAt != nil) != (tt.status == http.StatusOK) {
+			t.Errorf("%s: revoked %v, want %v", tt.name, key.RevokedAt != nil, tt.status == http.StatusOK)
+		}
+	}
+}
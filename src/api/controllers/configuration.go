@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/auth"
+	"github.com/lnpay-wrapper-api-go/src/api/config"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+)
+
+type ConfigReloadResponse struct {
+	// Changed lists the keys whose values changed, empty when the config file didn't change
+	Changed []string `json:"changed"`
+}
+
+// GetConfig is the handler to get the configuration in use
+// @Summary Get configuration
+// @Description returns the effective configuration, merged from the config file, the profile and the environment, with the secrets masked
+// @Tags admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} Envelope{data=map[string]interface{}}
+// @Failure 401 {object} apierrors.ApiError
+// @Failure 403 {object} apierrors.ApiError
+// @Router /admin/config [get]
+func GetConfig(c *gin.Context) {
+	respond(c, http.StatusOK, config.Current().Redacted().Values())
+}
+
+// ReloadConfig is the handler to reload the configuration
+// @Summary Reload configuration
+// @Description reads the config file again and applies it. An invalid configuration or a change to a key read on restart only is rejected and a failed reload is rolled back, the configuration in use is kept in both cases.
+// @Tags admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} Envelope{data=ConfigReloadResponse}
+// @Failure 400 {object} apierrors.ApiError
+// @Failure 401 {object} apierrors.ApiError
+// @Failure 403 {object} apierrors.ApiError
+// @Failure 500 {object} apierrors.ApiError
+// @Router /admin/config/reload [post]
+func ReloadConfig(c *gin.Context) {
+	identity, _ := auth.FromContext(c.Request.Context())
+	log := logger.WithContext(c.Request.Context())
+	log.Warn("Configuration reload requested", "key_id:"+identity.KeyID)
+
+	changed, err := config.Reload()
+	if err != nil {
+		var invalid config.ValidationError
+		if errors.As(err, &invalid) {
+			cause := make(apierrors.CauseList, 0, len(invalid.Problems))
+			for _, problem := range invalid.Problems {
+				cause = append(cause, problem)
+			}
+			respondError(c, apierrors.NewValidationApiError("Configuration reload rejected", "invalid_configuration", cause))
+			return
+		}
+		respondError(c, apierrors.NewInternalServerApiError("Configuration reload rolled back", err))
+		return
+	}
+	if changed == nil {
+		changed = []string{}
+	}
+	log.Info("Configuration reload finished", "key_id:"+identity.KeyID, "changed:"+strings.Join(changed, ","))
+	respond(c, http.StatusOK, ConfigReloadResponse{Changed: changed})
+}
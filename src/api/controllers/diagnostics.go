@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/diagnostics"
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+	"github.com/lnpay-wrapper-api-go/src/api/ratelimit"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/redact"
+)
+
+var (
+	rateLimiter    *ratelimit.Limiter
+	upstreamErrors *diagnostics.UpstreamErrors
+)
+
+// ConfigureDiagnostics sets the rate limiter and the upstream errors inspected by the diagnostics controllers.
+// The circuit breaker and the wallets are the ones set by ConfigureLNPay.
+func ConfigureDiagnostics(limiter *ratelimit.Limiter, errors *diagnostics.UpstreamErrors) {
+	rateLimiter = limiter
+	upstreamErrors = errors
+}
+
+type BudgetResponse struct {
+	Period string `json:"period"`
+	// PerKey and PerIP are the requests allowed every period, 0 when the limit is disabled
+	PerKey int `json:"per_key"`
+	PerIP  int `json:"per_ip"`
+}
+
+type RateLimitUsage struct {
+	Class      string `json:"class"`
+	Scope      string `json:"scope"`
+	Limit      int    `json:"limit"`
+	Remaining  int    `json:"remaining"`
+	Exhausted  bool   `json:"exhausted"`
+	RetryAfter string `json:"retry_after,omitempty"`
+}
+
+type RateLimitResponse struct {
+	Budgets map[string]BudgetResponse `json:"budgets"`
+	// Usage is the state of the counters of the key_id and ip asked for, in the current window
+	Usage []RateLimitUsage `json:"usage,omitempty"`
+}
+
+type LoadedWallet struct {
+	ID         string           `json:"id"`
+	UserLabel  string           `json:"user_label"`
+	CreatedAt  int              `json:"created_at"`
+	AccessKeys lnpay.AccessKeys `json:"access_keys"`
+}
+
+type UpstreamErrorsResponse struct {
+	// Total counts the errors since the start, only the most recent are kept
+	Total  int                         `json:"total"`
+	Errors []diagnostics.UpstreamError `json:"errors"`
+}
+
+// GetBreakerStatus is the handler to get the state of the lnpay circuit breaker
+// @Summary Get circuit breaker
+// @Description returns the state of the circuit breaker guarding the calls to lnpay, its consecutive failures and the last error
+// @Tags admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} Envelope{data=lnpay.BreakerStatus}
+// @Failure 401 {object} apierrors.ApiError
+// @Failure 403 {object} apierrors.ApiError
+// @Failure 404 {object} apierrors.ApiError
+// @Router /admin/diagnostics/breaker [get]
+func GetBreakerStatus(c *gin.Context) {
+	breaker := lnpayClient.Breaker()
+	if breaker == nil {
+		respondError(c, apierrors.NewNotFoundApiError("The circuit breaker is disabled"))
+		return
+	}
+	status := breaker.Status()
+	status.LastError = redact.String(status.LastError)
+	respond(c, http.StatusOK, status)
+}
+
+// GetRateLimits is the handler to get the state of the rate limiter
+// @Summary Get rate limits
+// @Description returns the budgets of every class of endpoints and, for the given api key id or ip, the requests left in the current window without counting one
+// @Tags admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param key_id query string false "api key id"
+// @Param ip query string false "client ip"
+// @Success 200 {object} Envelope{data=RateLimitResponse}
+// @Failure 401 {object} apierrors.ApiError
+// @Failure 403 {object} apierrors.ApiError
+// @Failure 503 {object} apierrors.ApiError
+// @Router /admin/diagnostics/ratelimit [get]
+func GetRateLimits(c *gin.Context) {
+	response := RateLimitResponse{Budgets: map[string]BudgetResponse{}}
+	for class, budget := range rateLimiter.Budgets() {
+		response.Budgets[string(class)] = BudgetResponse{
+			Period: budget.PerKey.Period.String(),
+			PerKey: budget.PerKey.Requests,
+			PerIP:  budget.PerIP.Requests,
+		}
+	}
+
+	keyId, ip := c.Query("key_id"), c.Query("ip")
+	if keyId != "" || ip != "" {
+		usages, err := rateLimiter.Peek(c.Request.Context(), keyId, ip)
+		if err != nil {
+			respondError(c, apierrors.Wrap(apierrors.NewServiceUnavailableApiError("The rate limiter backend is unavailable"), err))
+			return
+		}
+		for _, usage := range usages {
+			u := RateLimitUsage{
+				Class:     string(usage.Class),
+				Scope:     usage.Scope,
+				Limit:     usage.Limit.Requests,
+				Remaining: usage.Result.Remaining,
+				Exhausted: !usage.Result.Allowed,
+			}
+			if usage.Result.RetryAfter > 0 {
+				u.RetryAfter = usage.Result.RetryAfter.Round(time.Millisecond).String()
+			}
+			response.Usage = append(response.Usage, u)
+		}
+	}
+	respond(c, http.StatusOK, response)
+}
+
+// ListLoadedWallets is the handler to list the lnpay wallets known by the api
+// @Summary List loaded wallets
+// @Description returns the lnpay wallets known by the api with their access keys masked
+// @Tags admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} Envelope{data=[]LoadedWallet}
+// @Failure 401 {object} apierrors.ApiError
+// @Failure 403 {object} apierrors.ApiError
+// @Failure 500 {object} apierrors.ApiError
+// @Router /admin/wallets [get]
+func ListLoadedWallets(c *gin.Context) {
+	records, err := walletStore.List()
+	if err != nil {
+		respondError(c, apierrors.NewInternalServerApiError("Error listing wallets", err))
+		return
+	}
+	wallets := make([]LoadedWallet, 0, len(records))
+	for _, record := range records {
+		wallets = append(wallets, LoadedWallet{
+			ID:        record.ID,
+			UserLabel: record.UserLabel,
+			CreatedAt: record.CreatedAt,
+			AccessKeys: lnpay.AccessKeys{
+				WalletAdmin:   maskKeys(record.AccessKeys.WalletAdmin),
+				WalletInvoice: maskKeys(record.AccessKeys.WalletInvoice),
+				WalletRead:    maskKeys(record.AccessKeys.WalletRead),
+			},
+		})
+	}
+	respond(c, http.StatusOK, wallets)
+}
+
+// ListUpstreamErrors is the handler to list the recent errors of the calls to lnpay
+// @Summary List upstream errors
+// @Description returns the most recent failed calls to lnpay, the newest first, with their errors redacted
+// @Tags admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} Envelope{data=UpstreamErrorsResponse}
+// @Failure 401 {object} apierrors.ApiError
+// @Failure 403 {object} apierrors.ApiError
+// @Router /admin/diagnostics/upstream-errors [get]
+func ListUpstreamErrors(c *gin.Context) {
+	recent, total := upstreamErrors.Recent()
+	respond(c, http.StatusOK, UpstreamErrorsResponse{Total: total, Errors: recent})
+}
+
+func maskKeys(keys []string) []string {
+	masked := make([]string, 0, len(keys))
+	for _, key := range keys {
+		masked = append(masked, redact.Key(key))
+	}
+	return masked
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/lnpay-wrapper-api-go/src/api/events"
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+	"github.com/lnpay-wrapper-api-go/src/api/metrics"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/validation"
+)
+
+const keepAliveInterval = 15 * time.Second
+
+var errInvalidEventsToken = errors.New("invalid events token")
+
+var (
+	invoiceHub         *events.InvoiceHub
+	webhookQueue       *events.WebhookQueue
+	subscriptionTokens *events.SubscriptionTokens
+	upgrader           websocket.Upgrader
+)
+
+// ConfigureEvents sets the hub, webhook queue and subscription tokens used by the invoice events controllers.
+// allowedOrigins lists the origins allowed to open websockets, same origin only when empty.
+func ConfigureEvents(hub *events.InvoiceHub, queue *events.WebhookQueue, tokens *events.SubscriptionTokens, allowedOrigins []string) {
+	invoiceHub = hub
+	webhookQueue = queue
+	subscriptionTokens = tokens
+	upgrader = websocket.Upgrader{}
+	if len(allowedOrigins) > 0 {
+		origins := make(map[string]bool, len(allowedOrigins))
+		for _, origin := range allowedOrigins {
+			origins[origin] = true
+		}
+		upgrader.CheckOrigin = func(r *http.Request) bool {
+			return origins["*"] || origins[r.Header.Get("Origin")]
+		}
+	}
+}
+
+type webhookPayload struct {
+	lnpay.Webhook
+	Data struct {
+		Wtx *lnpay.Wtx `json:"wtx"`
+	} `json:"data"`
+}
+
+// InvoiceEvents is the handler of the server-sent events stream of an invoice
+// @Summary Invoice events
+// @Description streams the status changes (created, settled, expired) of an invoice as server-sent events. The stream ends once the invoice is settled or expired.
+// @Tags invoices
+// @Produce  text/event-stream
+// @Param lntxId path string true "lightning transaction id of the invoice"
+// @Param token query string true "events_token returned along with the invoice"
+// @Success 200 {object} events.InvoiceEvent
+// @Failure 401 {object} apierrors.ApiError
+// @Failure 404 {object} apierrors.ApiError
+// @Failure 500 {object} apierrors.ApiError
+// @Failure 503 {object} apierrors.ApiError
+// @Router /v1/invoices/{lntxId}/events [get]
+func InvoiceEvents(c *gin.Context) {
+	stream, cancel, err := subscribe(c)
+	if err != nil {
+		respondError(c, subscribeError(err))
+		return
+	}
+	defer cancel()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-stream:
+			if !ok {
+				return false
+			}
+			c.SSEvent(string(event.Status), event)
+			return !event.Final()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// InvoiceEventsWebsocket is the handler of the websocket alternative to InvoiceEvents
+// @Summary Invoice events websocket
+// @Description upgrades the connection to a websocket that receives the status changes of an invoice as JSON messages. The server closes it once the invoice is settled or expired.
+// @Tags invoices
+// @Param lntxId path string true "lightning transaction id of the invoice"
+// @Param token query string true "events_token returned along with the invoice"
+// @Success 101 {object} events.InvoiceEvent
+// @Failure 401 {object} apierrors.ApiError
+// @Failure 404 {object} apierrors.ApiError
+// @Failure 500 {object} apierrors.ApiError
+// @Failure 503 {object} apierrors.ApiError
+// @Router /v1/invoices/{lntxId}/ws [get]
+func InvoiceEventsWebsocket(c *gin.Context) {
+	stream, cancel, err := subscribe(c)
+	if err != nil {
+		respondError(c, subscribeError(err))
+		return
+	}
+	defer cancel()
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// the upgrader already answered the request
+		return
+	}
+	defer conn.Close()
+
+	closed := make(chan struct{})
+	go func() {
+		// we only read to notice when the client goes away
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case event, ok := <-stream:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+					time.Now().Add(time.Second))
+				return
+			}
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+			if event.Final() {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, string(event.Status)),
+					time.Now().Add(time.Second))
+				return
+			}
+		case <-keepAlive.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// subscribe checks the token of the request before subscribing, so lnpay isn't called for anybody else.
+func subscribe(c *gin.Context) (<-chan events.InvoiceEvent, func(), error) {
+	if !subscriptionTokens.Valid(c.Param("lntxId"), c.Query("token")) {
+		return nil, nil, errInvalidEventsToken
+	}
+	return invoiceHub.Subscribe(c.Param("lntxId"))
+}
+
+func subscribeError(err error) apierrors.ApiError {
+	switch err {
+	case errInvalidEventsToken:
+		return apierrors.NewUnauthorizedApiError("The token is missing, invalid or expired, create the invoice again to get one")
+	case events.ErrTooManySubscriptions:
+		return apierrors.NewServiceUnavailableApiError("Too many invoice streams open, retry later")
+	case events.ErrHubClosed:
+		return apierrors.NewServiceUnavailableApiError("Server is shutting down, reconnect to follow the invoice")
+	}
+	return fromLNPayError("Error getting invoice", err)
+}
+
+// LNPayWebhook is the handler receiving the lnpay webhooks
+// @Summary LNPay webhook receiver
+// @Description receives the lnpay webhooks and refreshes the watched invoices they refer to
+// @Tags webhooks
+// @Accept  json
+// @Produce  json
+// @Param request body lnpay.Webhook true "lnpay webhook"
+// @Success 202
+// @Failure 400 {object} apierrors.ApiError
+// @Failure 429 {object} apierrors.ApiError
+// @Failure 503 {object} apierrors.ApiError
+// @Router /v1/webhooks/lnpay [post]
+func LNPayWebhook(c *gin.Context) {
+	var payload webhookPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		metrics.Webhook("received", "invalid")
+		respondError(c, validation.FromBindingError(err))
+		return
+	}
+
+	if payload.Data.Wtx == nil || payload.Data.Wtx.LnTx.ID == "" {
+		metrics.Webhook("received", "ignored")
+	} else {
+		lntxId := payload.Data.Wtx.LnTx.ID
+		if err := webhookQueue.Enqueue(lntxId); err != nil {
+			logger.WithContext(c.Request.Context()).Component(logger.ComponentEvents).Error("Error queueing webhook "+payload.ID, err, "event:"+payload.Event.Name, "lntx_id:"+lntxId)
+			metrics.Webhook("received", "rejected")
+			respondError(c, apierrors.NewServiceUnavailableApiError("Webhook queue unavailable"))
+			return
+		}
+		metrics.Webhook("received", "queued")
+	}
+	c.Status(http.StatusAccepted)
+}
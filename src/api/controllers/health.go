@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/health"
+)
+
+var (
+	healthChecker *health.Checker
+	startedAt     = time.Now().UTC()
+)
+
+// ConfigureHealth sets the checker used by the readiness probe.
+func ConfigureHealth(checker *health.Checker) {
+	healthChecker = checker
+}
+
+type LivenessResponse struct {
+	Status        health.Status `json:"status"`
+	StartedAt     time.Time     `json:"started_at"`
+	UptimeSeconds int64         `json:"uptime_seconds"`
+}
+
+// Live is the handler of the liveness probe
+// @Summary Liveness probe
+// @Description reports the process is up, without checking its dependencies
+// @Tags health
+// @Produce  json
+// @Success 200 {object} LivenessResponse
+// @Router /health/live [get]
+func Live(c *gin.Context) {
+	c.JSON(http.StatusOK, LivenessResponse{
+		Status:        health.StatusUp,
+		StartedAt:     startedAt,
+		UptimeSeconds: int64(time.Since(startedAt).Seconds()),
+	})
+}
+
+// Ready is the handler of the readiness probe
+// @Summary Readiness probe
+// @Description reports the status of the api dependencies: lnpay, its circuit breaker, the storage backends, the webhook queue and the configuration. The lnpay check is cached. Answers 503 when a critical check is down.
+// @Tags health
+// @Produce  json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /health/ready [get]
+func Ready(c *gin.Context) {
+	report := healthChecker.Report(c.Request.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
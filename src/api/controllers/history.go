@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/pagination"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/validation"
+)
+
+const (
+	defaultHistoryLimit = 20
+	// minUpstreamPages is how many lnpay pages a single request can stitch together at least, so the
+	// filtered listings can skip some transactions.
+	minUpstreamPages = 5
+	pageCountHeader  = "X-Pagination-Page-Count"
+)
+
+type TransactionHistoryQuery struct {
+	Cursor    string `form:"cursor"`
+	Limit     int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Type      string `form:"type"`
+	From      int    `form:"from" binding:"omitempty,min=0"`
+	To        int    `form:"to" binding:"omitempty,min=0"`
+	MinAmount int64  `form:"min_amount" binding:"omitempty,min=0"`
+	MaxAmount int64  `form:"max_amount" binding:"omitempty,min=0"`
+}
+
+type PagedEnvelope struct {
+	Data   interface{}       `json:"data"`
+	Paging pagination.Paging `json:"paging"`
+}
+
+// ListWalletTransactions is the handler to list the transactions of a wallet
+// @Summary List wallet transactions
+// @Description returns the wallet transactions newest first, filtered and paginated with an opaque cursor
+// @Tags transactions
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param key path string true "wallet access key"
+// @Param cursor query string false "cursor returned by the previous page"
+// @Param limit query int false "page size, 1 to 100 (default 20)"
+// @Param type query string false "transaction type, e.g. ln_deposit, ln_withdrawal"
+// @Param from query int false "only transactions created at or after this unix timestamp"
+// @Param to query int false "only transactions created at or before this unix timestamp"
+// @Param min_amount query int false "minimum amount in satoshis"
+// @Param max_amount query int false "maximum amount in satoshis"
+// @Success 200 {object} PagedEnvelope{data=[]WalletTransactionResponse}
+// @Failure 400 {object} apierrors.ApiError
+// @Failure 403 {object} apierrors.ApiError
+// @Failure 429 {object} apierrors.ApiError
+// @Failure 500 {object} apierrors.ApiError
+// @Router /v1/wallets/{key}/transactions [get]
+func ListWalletTransactions(c *gin.Context) {
+	var query TransactionHistoryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondError(c, validation.FromBindingError(err))
+		return
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultHistoryLimit
+	}
+	if query.To > 0 && query.From > query.To {
+		respondError(c, apierrors.NewBadRequestApiError("from must be lower or equal than to"))
+		return
+	}
+	if query.MaxAmount > 0 && query.MinAmount > query.MaxAmount {
+		respondError(c, apierrors.NewBadRequestApiError("min_amount must be lower or equal than max_amount"))
+		return
+	}
+
+	position := pagination.Cursor{Page: 1, Filters: query.fingerprint()}
+	resuming := query.Cursor != ""
+	if resuming {
+		cursor, err := pagination.Decode(query.Cursor)
+		if err != nil || cursor.Filters != position.Filters {
+			respondError(c, apierrors.NewBadRequestApiError("Invalid cursor for the given filters"))
+			return
+		}
+		position = cursor
+	}
+
+	wallet := lnpayClient.WithContext(c.Request.Context()).Wallet(c.Param("key"))
+	items := make([]WalletTransactionResponse, 0, query.Limit)
+	hasMore := false
+
+	for fetched := 0; ; fetched++ {
+		txs, header, err := wallet.Transactions(position.Page)
+		if err != nil {
+			respondError(c, fromLNPayError("Error listing wallet transactions", err))
+			return
+		}
+		sortTransactions(txs)
+
+		exhausted := isLastPage(position.Page, len(txs), header)
+		full := false
+		for _, tx := range txs {
+			if resuming && !sortsAfter(tx, position) {
+				continue
+			}
+			if query.From > 0 && tx.CreatedAt < query.From {
+				// lnpay returns the newest transactions first, nothing older can match.
+				exhausted = true
+				break
+			}
+			if full {
+				if query.matches(tx) {
+					hasMore = true
+					break
+				}
+				continue
+			}
+
+			position.CreatedAt, position.ID = tx.CreatedAt, tx.ID
+			resuming = true
+			if query.matches(tx) {
+				items = append(items, newWalletTransactionResponse(tx))
+				full = len(items) == query.Limit
+			}
+		}
+
+		if full {
+			hasMore = hasMore || !exhausted
+			break
+		}
+		if exhausted {
+			break
+		}
+		position.Page++
+		if fetched+1 == upstreamPages(query.Limit) {
+			hasMore = true
+			break
+		}
+	}
+
+	paging := pagination.Paging{Limit: query.Limit, HasMore: hasMore}
+	if hasMore {
+		paging.NextCursor = position.Encode()
+	}
+	c.JSON(http.StatusOK, PagedEnvelope{Data: items, Paging: paging})
+}
+
+func (q TransactionHistoryQuery) matches(tx lnpay.Wtx) bool {
+	if q.Type != "" && tx.WtxType.Name != q.Type {
+		return false
+	}
+	if q.To > 0 && tx.CreatedAt > q.To {
+		return false
+	}
+	if q.MinAmount > 0 && abs(tx.NumSatoshis) < q.MinAmount {
+		return false
+	}
+	if q.MaxAmount > 0 && abs(tx.NumSatoshis) > q.MaxAmount {
+		return false
+	}
+	return true
+}
+
+func (q TransactionHistoryQuery) fingerprint() string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s|%d|%d|%d|%d", q.Type, q.From, q.To, q.MinAmount, q.MaxAmount)
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+// sortTransactions orders the transactions newest first, using the id to break ties.
+func sortTransactions(txs []lnpay.Wtx) {
+	sort.SliceStable(txs, func(i, j int) bool {
+		if txs[i].CreatedAt != txs[j].CreatedAt {
+			return txs[i].CreatedAt > txs[j].CreatedAt
+		}
+		return txs[i].ID > txs[j].ID
+	})
+}
+
+// sortsAfter reports whether tx comes after the last item returned at the cursor position.
+func sortsAfter(tx lnpay.Wtx, position pagination.Cursor) bool {
+	if tx.CreatedAt != position.CreatedAt {
+		return tx.CreatedAt < position.CreatedAt
+	}
+	return tx.ID < position.ID
+}
+
+// upstreamPages bounds how many lnpay pages a request for limit transactions stitches together: the pages
+// holding limit transactions, plus one since a cursor resumes in the middle of a page.
+func upstreamPages(limit int) int {
+	pages := (limit+lnpay.TransactionsPerPage-1)/lnpay.TransactionsPerPage + 1
+	if pages < minUpstreamPages {
+		return minUpstreamPages
+	}
+	return pages
+}
+
+func isLastPage(page int, size int, header http.Header) bool {
+	if size == 0 {
+		return true
+	}
+	pageCount, err := strconv.Atoi(header.Get(pageCountHeader))
+	if err != nil {
+		return false
+	}
+	return page >= pageCount
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+	"github.com/lnpay-wrapper-api-go/src/api/storage"
+)
+
+// serveTransactions fakes lnpay answering pages of a wallet holding total transactions, newest first.
+func serveTransactions(t *testing.T, total int) *httptest.Server {
+	t.Helper()
+	pageCount := (total + lnpay.TransactionsPerPage - 1) / lnpay.TransactionsPerPage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		txs := []lnpay.Wtx{}
+		for i := (page - 1) * lnpay.TransactionsPerPage; i < page*lnpay.TransactionsPerPage && i < total; i++ {
+			txs = append(txs, lnpay.Wtx{ID: fmt.Sprintf("wtx_%03d", i), CreatedAt: 1000000 - i, NumSatoshis: 1})
+		}
+		w.Header().Set(pageCountHeader, strconv.Itoa(pageCount))
+		json.NewEncoder(w).Encode(txs)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestListWalletTransactionsLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name    string
+		total   int
+		limit   int
+		want    int
+		hasMore bool
+	}{
+		{"max limit", 250, 100, 100, true},
+		{"max limit of the last transactions", 100, 100, 100, false},
+		{"fewer transactions than the limit", 45, 100, 45, false},
+		{"default limit", 250, 0, defaultHistoryLimit, true},
+	}
+	for _, tt := range tests {
+		client := lnpay.NewClient("sak_test")
+		client.SetBaseURL(serveTransactions(t, tt.total).URL)
+		ConfigureLNPay(client, storage.NewMemoryWalletStore())
+
+		target := "/v1/wallets/waka_shop/transactions"
+		if tt.limit > 0 {
+			target += "?limit=" + strconv.Itoa(tt.limit)
+		}
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+		c.Params = gin.Params{{Key: "key", Value: "waka_shop"}}
+		ListWalletTransactions(c)
+
+		var body struct {
+			Data   []WalletTransactionResponse `json:"data"`
+			Paging struct {
+				HasMore bool `json:"has_more"`
+			} `json:"paging"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("%s: got %d %s", tt.name, w.Code, w.Body.String())
+		}
+		if len(body.Data) != tt.want || body.Paging.HasMore != tt.hasMore {
+			t.Errorf("%s: got %d transactions and more %v, want %d and %v", tt.name, len(body.Data), body.Paging.HasMore, tt.want, tt.hasMore)
+		}
+	}
+}
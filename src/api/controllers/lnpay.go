@@ -1 +1,156 @@
 package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/auth"
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+	"github.com/lnpay-wrapper-api-go/src/api/storage"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/validation"
+)
+
+var (
+	lnpayClient *lnpay.Client
+	walletStore storage.WalletStore
+)
+
+// ConfigureLNPay sets the lnpay client and the wallet store used by the lnpay controllers.
+func ConfigureLNPay(client *lnpay.Client, wallets storage.WalletStore) {
+	lnpayClient = client
+	walletStore = wallets
+}
+
+type CreateWalletRequest struct {
+	Label string `json:"label" binding:"required,max=64"`
+}
+
+type WalletResponse struct {
+	ID         string            `json:"id"`
+	UserLabel  string            `json:"user_label"`
+	CreatedAt  int               `json:"created_at"`
+	UpdatedAt  int               `json:"updated_at,omitempty"`
+	Balance    int64             `json:"balance"`
+	Status     string            `json:"status,omitempty"`
+	AccessKeys *lnpay.AccessKeys `json:"access_keys,omitempty"`
+}
+
+type WalletSummary struct {
+	ID        string `json:"id"`
+	UserLabel string `json:"user_label"`
+	CreatedAt int    `json:"created_at"`
+}
+
+// CreateWallet is the handler to create a new lnpay wallet
+// @Summary Create wallet
+// @Description creates a new lnpay wallet with the given label and returns its access keys
+// @Tags wallets
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param request body CreateWalletRequest true "wallet to create"
+// @Success 201 {object} Envelope{data=WalletResponse}
+// @Failure 400 {object} apierrors.ApiError
+// @Failure 500 {object} apierrors.ApiError
+// @Failure 502 {object} apierrors.ApiError
+// @Router /v1/wallets [post]
+func CreateWallet(c *gin.Context) {
+	var request CreateWalletRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, validation.FromBindingError(err))
+		return
+	}
+
+	wallet, err := lnpayClient.WithContext(c.Request.Context()).CreateWallet(request.Label)
+	if err != nil {
+		respondError(c, fromLNPayError("Error creating wallet", err))
+		return
+	}
+
+	record := storage.WalletRecord{
+		ID:         wallet.ID,
+		UserLabel:  wallet.UserLabel,
+		CreatedAt:  wallet.CreatedAt,
+		AccessKeys: wallet.AccessKeys,
+	}
+	if err := walletStore.Save(record); err != nil {
+		logger.WithContext(c.Request.Context()).Error("Error saving wallet "+wallet.ID, err)
+	}
+
+	response := newWalletResponse(wallet)
+	response.AccessKeys = &wallet.AccessKeys
+	respond(c, http.StatusCreated, response)
+}
+
+// GetWallet is the handler to fetch the details and balance of a wallet
+// @Summary Get wallet
+// @Description returns the details and balance of the wallet identified by any of its access keys
+// @Tags wallets
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param key path string true "wallet access key"
+// @Success 200 {object} Envelope{data=WalletResponse}
+// @Failure 403 {object} apierrors.ApiError
+// @Failure 404 {object} apierrors.ApiError
+// @Failure 500 {object} apierrors.ApiError
+// @Router /v1/wallets/{key} [get]
+func GetWallet(c *gin.Context) {
+	wallet, err := lnpayClient.WithContext(c.Request.Context()).Wallet(c.Param("key")).Details()
+	if err != nil {
+		respondError(c, fromLNPayError("Error getting wallet", err))
+		return
+	}
+
+	respond(c, http.StatusOK, newWalletResponse(wallet))
+}
+
+// ListWallets is the handler to list the wallets created through this api
+// @Summary List wallets
+// @Description returns the wallets created through this api
+// @Tags wallets
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} Envelope{data=[]WalletSummary}
+// @Failure 500 {object} apierrors.ApiError
+// @Router /v1/wallets [get]
+func ListWallets(c *gin.Context) {
+	records, err := walletStore.List()
+	if err != nil {
+		respondError(c, apierrors.NewInternalServerApiError("Error listing wallets", err))
+		return
+	}
+
+	identity, _ := auth.FromContext(c.Request.Context())
+	wallets := make([]WalletSummary, 0, len(records))
+	for _, record := range records {
+		if !identity.CanAccessWallet(walletIdentifiers(record)...) {
+			continue
+		}
+		wallets = append(wallets, WalletSummary{
+			ID:        record.ID,
+			UserLabel: record.UserLabel,
+			CreatedAt: record.CreatedAt,
+		})
+	}
+	respond(c, http.StatusOK, wallets)
+}
+
+func newWalletResponse(wallet lnpay.Wallet) WalletResponse {
+	return WalletResponse{
+		ID:        wallet.ID,
+		UserLabel: wallet.UserLabel,
+		CreatedAt: wallet.CreatedAt,
+		UpdatedAt: wallet.UpdatedAt,
+		Balance:   wallet.Balance,
+		Status:    wallet.StatusType.Name,
+	}
+}
+
+func walletIdentifiers(record storage.WalletRecord) []string {
+	ids := []string{record.ID}
+	ids = append(ids, record.AccessKeys.WalletAdmin...)
+	ids = append(ids, record.AccessKeys.WalletInvoice...)
+	return append(ids, record.AccessKeys.WalletRead...)
+}
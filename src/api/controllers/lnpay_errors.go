@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+)
+
+// lnpayErrors maps the status of the lnpay errors to the ApiError we answer with.
+// lnpay answers 401 and 403 when the wallet key doesn't allow the operation; our own
+// caller is authenticated, so both become a forbidden error.
+var lnpayErrors = map[int]func(message string) apierrors.ApiError{
+	http.StatusBadRequest:          apierrors.NewBadRequestApiError,
+	http.StatusUnprocessableEntity: apierrors.NewBadRequestApiError,
+	http.StatusUnauthorized:        apierrors.NewForbiddenApiError,
+	http.StatusForbidden:           apierrors.NewForbiddenApiError,
+	http.StatusNotFound:            apierrors.NewNotFoundApiError,
+	http.StatusTooManyRequests:     apierrors.NewTooManyRequestsError,
+}
+
+// fromLNPayError translates an error returned by the lnpay client into an ApiError wrapping it.
+// Invalid params become validation errors, an open circuit becomes a service unavailable error, an
+// unusable answer becomes a bad gateway error and errors without a known mapping become internal server errors.
+func fromLNPayError(message string, err error) apierrors.ApiError {
+	if errors.Is(err, lnpay.ErrCircuitOpen) {
+		return apierrors.Wrap(apierrors.NewServiceUnavailableApiError(message+": lnpay is unavailable, retry later"), err)
+	}
+
+	if errors.Is(err, lnpay.ErrInvalidAnswer) {
+		return apierrors.NewBadGatewayApiError(message+": invalid answer from lnpay", err)
+	}
+
+	var validationErrs lnpay.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		causes := make([]apierrors.FieldCause, 0, len(validationErrs))
+		for _, e := range validationErrs {
+			causes = append(causes, apierrors.FieldCause{Field: e.Field, Code: e.Code, Message: e.Message})
+		}
+		return apierrors.Wrap(apierrors.NewFieldValidationApiError(message+": invalid params", causes...), err)
+	}
+
+	var lnpayErr lnpay.Error
+	if !errors.As(err, &lnpayErr) {
+		return apierrors.NewInternalServerApiError(message, err)
+	}
+
+	build, ok := lnpayErrors[lnpayErr.Status]
+	if !ok {
+		return apierrors.NewInternalServerApiError(message, err)
+	}
+	apiErr := build(fmt.Sprintf("%s: %s", message, lnpayErr.Message))
+	cause := apierrors.CauseList{apierrors.FieldCause{Code: "lnpay_error", Message: lnpayErr.Message}}
+	return apierrors.Wrap(apierrors.NewApiError(apiErr.Message(), apiErr.Code(), apiErr.Status(), cause), err)
+}
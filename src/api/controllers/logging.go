@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/auth"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/validation"
+)
+
+type LogLevelsRequest struct {
+	Level string `json:"level" binding:"required,oneof=panic fatal error warn warning info debug trace"`
+	// Components override the level for the entries of some components, like lnpay or http
+	Components map[string]string `json:"components" binding:"dive,keys,required,endkeys,oneof=panic fatal error warn warning info debug trace"`
+}
+
+type LogLevelsResponse struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components"`
+}
+
+// GetLogLevels is the handler to get the log levels
+// @Summary Get log levels
+// @Description returns the level of the logger and the levels overriding it for some components
+// @Tags admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} Envelope{data=LogLevelsResponse}
+// @Failure 401 {object} apierrors.ApiError
+// @Failure 403 {object} apierrors.ApiError
+// @Router /admin/log-levels [get]
+func GetLogLevels(c *gin.Context) {
+	respond(c, http.StatusOK, newLogLevelsResponse(logger.GetLevels()))
+}
+
+// SetLogLevels is the handler to change the log levels
+// @Summary Set log levels
+// @Description replaces the level of the logger and the component overrides until the next restart or config file change
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param request body LogLevelsRequest true "log levels"
+// @Success 200 {object} Envelope{data=LogLevelsResponse}
+// @Failure 400 {object} apierrors.ApiError
+// @Failure 401 {object} apierrors.ApiError
+// @Failure 403 {object} apierrors.ApiError
+// @Router /admin/log-levels [put]
+func SetLogLevels(c *gin.Context) {
+	var request LogLevelsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, validation.FromBindingError(err))
+		return
+	}
+
+	err := logger.SetLevels(logger.Levels{Level: request.Level, Components: request.Components})
+	if err != nil {
+		respondError(c, apierrors.NewBadRequestApiError(err.Error()))
+		return
+	}
+	identity, _ := auth.FromContext(c.Request.Context())
+	logger.WithContext(c.Request.Context()).Warn("Log levels changed", "log_level:"+request.Level, "key_id:"+identity.KeyID)
+	respond(c, http.StatusOK, newLogLevelsResponse(logger.GetLevels()))
+}
+
+func newLogLevelsResponse(levels logger.Levels) LogLevelsResponse {
+	return LogLevelsResponse{Level: levels.Level, Components: levels.Components}
+}
@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/validation"
+)
+
+type DecodeInvoiceQuery struct {
+	PaymentRequest string `form:"payment_request" binding:"required,bolt11"`
+}
+
+type QueryRoutesQuery struct {
+	PubKey string `form:"pub_key" binding:"required,len=66,hexadecimal"`
+	Amt    int64  `form:"amt" binding:"required,gt=0"`
+}
+
+type HopHintResponse struct {
+	NodeID                    string `json:"node_id"`
+	ChanID                    string `json:"chan_id"`
+	FeeProportionalMillionths int    `json:"fee_proportional_millionths"`
+	CltvExpiryDelta           int    `json:"cltv_expiry_delta"`
+}
+
+type FeatureResponse struct {
+	Bit     int    `json:"bit"`
+	Name    string `json:"name"`
+	IsKnown bool   `json:"is_known"`
+}
+
+type DecodedInvoiceResponse struct {
+	Destination string              `json:"destination"`
+	PaymentHash string              `json:"payment_hash"`
+	PaymentAddr string              `json:"payment_addr"`
+	NumSatoshis int64               `json:"num_satoshis"`
+	NumMsat     int64               `json:"num_msat"`
+	Timestamp   int64               `json:"timestamp"`
+	Expiry      int64               `json:"expiry"`
+	ExpiresAt   int64               `json:"expires_at"`
+	Description string              `json:"description"`
+	CltvExpiry  int64               `json:"cltv_expiry"`
+	RouteHints  [][]HopHintResponse `json:"route_hints"`
+	Features    []FeatureResponse   `json:"features"`
+}
+
+type HopResponse struct {
+	ChanID           string `json:"chan_id"`
+	ChanCapacity     int64  `json:"chan_capacity"`
+	PubKey           string `json:"pub_key"`
+	AmtToForward     int64  `json:"amt_to_forward"`
+	AmtToForwardMsat int64  `json:"amt_to_forward_msat"`
+	FeeMsat          int64  `json:"fee_msat"`
+	Expiry           int    `json:"expiry"`
+	TlvPayload       bool   `json:"tlv_payload"`
+}
+
+type RouteResponse struct {
+	TotalTimeLock int           `json:"total_time_lock"`
+	TotalAmt      int64         `json:"total_amt"`
+	TotalAmtMsat  int64         `json:"total_amt_msat"`
+	TotalFees     int64         `json:"total_fees"`
+	TotalFeesMsat int64         `json:"total_fees_msat"`
+	Hops          []HopResponse `json:"hops"`
+}
+
+type RoutesResponse struct {
+	Routes             []RouteResponse `json:"routes"`
+	MinTotalFeesMsat   int64           `json:"min_total_fees_msat"`
+	SuccessProbability float64         `json:"success_probability"`
+}
+
+// DecodeInvoice is the handler to decode a BOLT11 payment request
+// @Summary Decode invoice
+// @Description decodes a BOLT11 payment request
+// @Tags invoices
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param payment_request query string true "BOLT11 payment request"
+// @Success 200 {object} Envelope{data=DecodedInvoiceResponse}
+// @Failure 400 {object} apierrors.ApiError
+// @Failure 429 {object} apierrors.ApiError
+// @Failure 500 {object} apierrors.ApiError
+// @Failure 502 {object} apierrors.ApiError
+// @Router /v1/invoices/decode [get]
+func DecodeInvoice(c *gin.Context) {
+	var query DecodeInvoiceQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondError(c, validation.FromBindingError(err))
+		return
+	}
+
+	invoice, err := lnpayClient.WithContext(c.Request.Context()).DecodeInvoice(query.PaymentRequest)
+	if err != nil {
+		respondError(c, fromLNPayError("Error decoding invoice", err))
+		return
+	}
+
+	response, err := newDecodedInvoiceResponse(invoice)
+	if err != nil {
+		respondError(c, apierrors.NewBadGatewayApiError("Invalid decoded invoice returned by lnpay", err))
+		return
+	}
+	respond(c, http.StatusOK, response)
+}
+
+// QueryRoutes is the handler to probe the routes to a node
+// @Summary Query routes
+// @Description returns the routes available to pay amt satoshis to the node with the given public key
+// @Tags routes
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param pub_key query string true "destination node public key"
+// @Param amt query int true "amount in satoshis"
+// @Success 200 {object} Envelope{data=RoutesResponse}
+// @Failure 400 {object} apierrors.ApiError
+// @Failure 429 {object} apierrors.ApiError
+// @Failure 500 {object} apierrors.ApiError
+// @Failure 502 {object} apierrors.ApiError
+// @Router /v1/routes [get]
+func QueryRoutes(c *gin.Context) {
+	var query QueryRoutesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondError(c, validation.FromBindingError(err))
+		return
+	}
+
+	routes, err := lnpayClient.WithContext(c.Request.Context()).QueryRoutes(query.PubKey, strconv.FormatInt(query.Amt, 10))
+	if err != nil {
+		respondError(c, fromLNPayError("Error querying routes", err))
+		return
+	}
+
+	response, err := newRoutesResponse(routes)
+	if err != nil {
+		respondError(c, apierrors.NewBadGatewayApiError("Invalid routes returned by lnpay", err))
+		return
+	}
+	respond(c, http.StatusOK, response)
+}
+
+func newDecodedInvoiceResponse(invoice lnpay.Invoice) (DecodedInvoiceResponse, error) {
+	var numbers numberParser
+	response := DecodedInvoiceResponse{
+		Destination: invoice.Destination,
+		PaymentHash: invoice.PaymentHash,
+		PaymentAddr: invoice.PaymentAddr,
+		NumSatoshis: numbers.parse("num_satoshis", invoice.NumSatoshis),
+		NumMsat:     numbers.parse("num_msat", invoice.NumMsat),
+		Timestamp:   numbers.parse("timestamp", invoice.Timestamp),
+		Expiry:      numbers.parse("expiry", invoice.Expiry),
+		Description: invoice.Description,
+		CltvExpiry:  numbers.parse("cltv_expiry", invoice.CltvExpiry),
+		RouteHints:  make([][]HopHintResponse, 0, len(invoice.RouteHints)),
+		Features:    []FeatureResponse{},
+	}
+	response.ExpiresAt = response.Timestamp + response.Expiry
+
+	for _, routeHint := range invoice.RouteHints {
+		hints := make([]HopHintResponse, 0, len(routeHint.HopHints))
+		for _, hint := range routeHint.HopHints {
+			hints = append(hints, HopHintResponse(hint))
+		}
+		response.RouteHints = append(response.RouteHints, hints)
+	}
+
+	features := []struct {
+		bit     int
+		name    string
+		isKnown bool
+	}{
+		{9, invoice.Features.Num9.Name, invoice.Features.Num9.IsKnown},
+		{15, invoice.Features.Num15.Name, invoice.Features.Num15.IsKnown},
+		{17, invoice.Features.Num17.Name, invoice.Features.Num17.IsKnown},
+	}
+	for _, feature := range features {
+		if feature.name != "" {
+			response.Features = append(response.Features, FeatureResponse{feature.bit, feature.name, feature.isKnown})
+		}
+	}
+	return response, numbers.err
+}
+
+func newRoutesResponse(routes lnpay.QueryRoutes) (RoutesResponse, error) {
+	var numbers numberParser
+	response := RoutesResponse{
+		Routes:             make([]RouteResponse, 0, len(routes.Routes)),
+		SuccessProbability: routes.Successprob,
+	}
+
+	for _, route := range routes.Routes {
+		totalFeesMsat := numbers.parse("total_fees_msat", route.Totalfeesmsat)
+		r := RouteResponse{
+			TotalTimeLock: route.Totaltimelock,
+			TotalAmt:      numbers.parse("total_amt", route.Totalamt),
+			TotalAmtMsat:  numbers.parse("total_amt_msat", route.Totalamtmsat),
+			TotalFees:     totalFeesMsat / 1000,
+			TotalFeesMsat: totalFeesMsat,
+			Hops:          make([]HopResponse, 0, len(route.Hops)),
+		}
+		for _, hop := range route.Hops {
+			r.Hops = append(r.Hops, HopResponse{
+				ChanID:           hop.Chanid,
+				ChanCapacity:     numbers.parse("chan_capacity", hop.Chancapacity),
+				PubKey:           hop.Pubkey,
+				AmtToForward:     numbers.parse("amt_to_forward", hop.Amttoforward),
+				AmtToForwardMsat: numbers.parse("amt_to_forward_msat", hop.Amttoforwardmsat),
+				FeeMsat:          numbers.parse("fee_msat", hop.Feemsat),
+				Expiry:           hop.Expiry,
+				TlvPayload:       hop.Tlvpayload,
+			})
+		}
+		response.Routes = append(response.Routes, r)
+	}
+
+	sort.SliceStable(response.Routes, func(i, j int) bool {
+		return response.Routes[i].TotalFeesMsat < response.Routes[j].TotalFeesMsat
+	})
+	if len(response.Routes) > 0 {
+		response.MinTotalFeesMsat = response.Routes[0].TotalFeesMsat
+	}
+	return response, numbers.err
+}
+
+// numberParser converts the numeric strings returned by lnd, treating empty values as zero.
+// err keeps the first value that isn't a number, so a broken answer isn't passed off as zeros.
+type numberParser struct {
+	err error
+}
+
+func (p *numberParser) parse(field string, value string) int64 {
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("%s: %w", field, err)
+	}
+	return n
+}
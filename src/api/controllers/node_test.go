@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+)
+
+func TestNewRoutesResponseRejectsInvalidNumbers(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer string
+		valid  bool
+	}{
+		{"numbers", `{"routes":[{"totalAmt":"10","totalFeesMsat":"2000","hops":[{"chanCapacity":"5","feeMsat":"1000"}]}]}`, true},
+		{"empty values", `{"routes":[{"totalAmt":"","hops":[{"feeMsat":""}]}]}`, true},
+		{"route amount", `{"routes":[{"totalAmt":"ten"}]}`, false},
+		{"hop fee", `{"routes":[{"totalAmt":"10","hops":[{"feeMsat":"1e3"}]}]}`, false},
+	}
+	for _, tt := range tests {
+		var routes lnpay.QueryRoutes
+		if err := json.Unmarshal([]byte(tt.answer), &routes); err != nil {
+			t.Fatal(err)
+		}
+		_, err := newRoutesResponse(routes)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: got error %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestNewDecodedInvoiceResponseRejectsInvalidNumbers(t *testing.T) {
+	response, err := newDecodedInvoiceResponse(lnpay.Invoice{NumSatoshis: "21", Timestamp: "100", Expiry: "3600"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.NumSatoshis != 21 || response.ExpiresAt != 3700 {
+		t.Errorf("got %d sats expiring at %d, want 21 at 3700", response.NumSatoshis, response.ExpiresAt)
+	}
+
+	_, err = newDecodedInvoiceResponse(lnpay.Invoice{NumSatoshis: "21", Expiry: "-"})
+	if err == nil || err.Error() != `expiry: strconv.ParseInt: parsing "-": invalid syntax` {
+		t.Errorf("got error %v, want the expiry rejected", err)
+	}
+}
@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	mw "github.com/lnpay-wrapper-api-go/src/api/middlewares"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+)
+
+// Envelope is the body of every successful response of the v1 api.
+type Envelope struct {
+	Data interface{} `json:"data"`
+}
+
+func respond(c *gin.Context, status int, data interface{}) {
+	c.JSON(status, Envelope{Data: data})
+}
+
+func respondError(c *gin.Context, apiErr apierrors.ApiError) {
+	mw.Render(c, apiErr)
+}
@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+	"github.com/lnpay-wrapper-api-go/src/api/metrics"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/validation"
+)
+
+type CreateInvoiceRequest struct {
+	// Memo is at most 639 bytes long, the longest description an invoice can carry
+	Memo            string                 `json:"memo" binding:"maxbytes=639"`
+	NumSatoshis     int64                  `json:"num_satoshis" binding:"amount"`
+	Expiry          int64                  `json:"expiry" binding:"gte=0"`
+	PassThru        map[string]interface{} `json:"passThru"`
+	DescriptionHash string                 `json:"description_hash" binding:"omitempty,b64hash,excluded_with=Memo"`
+}
+
+type PayRequest struct {
+	PaymentRequest string                 `json:"payment_request" binding:"required,bolt11"`
+	PassThru       map[string]interface{} `json:"passThru"`
+}
+
+type TransferRequest struct {
+	// Memo is at most 639 bytes long, like the memo of an invoice
+	Memo         string `json:"memo" binding:"maxbytes=639"`
+	NumSatoshis  int64  `json:"num_satoshis" binding:"amount"`
+	DestWalletId string `json:"dest_wallet_id" binding:"required,walletkey"`
+}
+
+type LnTxResponse struct {
+	ID              string                 `json:"id"`
+	CreatedAt       int                    `json:"created_at"`
+	PaymentRequest  string                 `json:"payment_request"`
+	PaymentHash     string                 `json:"payment_hash"`
+	Memo            string                 `json:"memo,omitempty"`
+	DescriptionHash string                 `json:"description_hash,omitempty"`
+	NumSatoshis     int64                  `json:"num_satoshis"`
+	Expiry          int                    `json:"expiry"`
+	ExpiresAt       int                    `json:"expires_at"`
+	PaymentPreimage string                 `json:"payment_preimage,omitempty"`
+	Settled         bool                   `json:"settled"`
+	SettledAt       int                    `json:"settled_at,omitempty"`
+	IsKeysend       bool                   `json:"is_keysend"`
+	CustomRecords   map[string]interface{} `json:"custom_records,omitempty"`
+}
+
+// InvoiceResponse is the created invoice along with the token to follow its events.
+type InvoiceResponse struct {
+	LnTxResponse
+	EventsToken          string `json:"events_token"`
+	EventsTokenExpiresAt int64  `json:"events_token_expires_at"`
+}
+
+type WalletTransactionResponse struct {
+	ID          string                 `json:"id"`
+	CreatedAt   int                    `json:"created_at"`
+	NumSatoshis int64                  `json:"num_satoshis"`
+	WalletID    string                 `json:"wallet_id"`
+	Type        string                 `json:"type"`
+	Layer       string                 `json:"layer"`
+	UserLabel   string                 `json:"user_label,omitempty"`
+	LnTx        *LnTxResponse          `json:"ln_tx,omitempty"`
+	PassThru    map[string]interface{} `json:"passThru,omitempty"`
+}
+
+// CreateInvoice is the handler to generate an invoice for a wallet
+// @Summary Create invoice
+// @Description generates a lightning invoice that pays into the wallet. When description_hash is set the memo is ignored. events_token opens the invoice events streams until it expires.
+// @Tags invoices
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param key path string true "wallet admin or invoice key"
+// @Param Idempotency-Key header string false "retries with the same key replay the first response"
+// @Param request body CreateInvoiceRequest true "invoice to create"
+// @Success 201 {object} Envelope{data=InvoiceResponse}
+// @Failure 400 {object} apierrors.ApiError
+// @Failure 403 {object} apierrors.ApiError
+// @Failure 429 {object} apierrors.ApiError
+// @Failure 500 {object} apierrors.ApiError
+// @Failure 503 {object} apierrors.ApiError
+// @Router /v1/wallets/{key}/invoices [post]
+func CreateInvoice(c *gin.Context) {
+	var request CreateInvoiceRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, validation.FromBindingError(err))
+		return
+	}
+
+	lntx, err := lnpayClient.WithContext(c.Request.Context()).Wallet(c.Param("key")).Invoice(lnpay.InvoiceParams{
+		Memo:            request.Memo,
+		NumSatoshis:     request.NumSatoshis,
+		Expiry:          request.Expiry,
+		PassThru:        request.PassThru,
+		DescriptionHash: request.DescriptionHash,
+	})
+	if err != nil {
+		respondError(c, fromLNPayError("Error creating invoice", err))
+		return
+	}
+
+	metrics.Sats("invoiced", lntx.NumSatoshis)
+	token, expiresAt := subscriptionTokens.Issue(lntx.ID)
+	respond(c, http.StatusCreated, InvoiceResponse{
+		LnTxResponse:         newLnTxResponse(lntx),
+		EventsToken:          token,
+		EventsTokenExpiresAt: expiresAt.Unix(),
+	})
+}
+
+// CreatePayment is the handler to pay an invoice with funds of a wallet
+// @Summary Pay invoice
+// @Description pays the given BOLT11 payment request with funds from the wallet
+// @Tags payments
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param key path string true "wallet admin key"
+// @Param Idempotency-Key header string false "retries with the same key replay the first response"
+// @Param request body PayRequest true "payment to make"
+// @Success 201 {object} Envelope{data=WalletTransactionResponse}
+// @Failure 400 {object} apierrors.ApiError
+// @Failure 403 {object} apierrors.ApiError
+// @Failure 429 {object} apierrors.ApiError
+// @Failure 500 {object} apierrors.ApiError
+// @Failure 503 {object} apierrors.ApiError
+// @Router /v1/wallets/{key}/payments [post]
+func CreatePayment(c *gin.Context) {
+	var request PayRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, validation.FromBindingError(err))
+		return
+	}
+
+	wtx, err := lnpayClient.WithContext(c.Request.Context()).Wallet(c.Param("key")).Pay(lnpay.PayParams{
+		PaymentRequest: request.PaymentRequest,
+		PassThru:       request.PassThru,
+	})
+	if err != nil {
+		respondError(c, fromLNPayError("Error paying invoice", err))
+		return
+	}
+
+	metrics.Sats("paid", wtx.NumSatoshis)
+	respond(c, http.StatusCreated, newWalletTransactionResponse(wtx))
+}
+
+// CreateTransfer is the handler to transfer funds between two lnpay wallets
+// @Summary Transfer between wallets
+// @Description moves funds from the wallet to another lnpay wallet
+// @Tags transfers
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param key path string true "wallet admin key"
+// @Param Idempotency-Key header string false "retries with the same key replay the first response"
+// @Param request body TransferRequest true "transfer to make"
+// @Success 201 {object} Envelope{data=WalletTransactionResponse}
+// @Failure 400 {object} apierrors.ApiError
+// @Failure 403 {object} apierrors.ApiError
+// @Failure 429 {object} apierrors.ApiError
+// @Failure 500 {object} apierrors.ApiError
+// @Failure 503 {object} apierrors.ApiError
+// @Router /v1/wallets/{key}/transfers [post]
+func CreateTransfer(c *gin.Context) {
+	var request TransferRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, validation.FromBindingError(err))
+		return
+	}
+
+	wtx, err := lnpayClient.WithContext(c.Request.Context()).Wallet(c.Param("key")).Transfer(lnpay.TransferParams{
+		Memo:         request.Memo,
+		NumSatoshis:  request.NumSatoshis,
+		DestWalletId: request.DestWalletId,
+	})
+	if err != nil {
+		respondError(c, fromLNPayError("Error transferring funds", err))
+		return
+	}
+
+	metrics.Sats("transferred", wtx.NumSatoshis)
+	respond(c, http.StatusCreated, newWalletTransactionResponse(wtx))
+}
+
+// GetTransaction is the handler to fetch a lightning transaction
+// @Summary Get lightning transaction
+// @Description returns the lightning transaction (invoice or payment) with the given id. Lnpay doesn't tell the wallet of the transaction, so the api keys restricted to some wallets can't use it.
+// @Tags transactions
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param lntxId path string true "lightning transaction id"
+// @Success 200 {object} Envelope{data=LnTxResponse}
+// @Failure 403 {object} apierrors.ApiError
+// @Failure 404 {object} apierrors.ApiError
+// @Failure 429 {object} apierrors.ApiError
+// @Failure 500 {object} apierrors.ApiError
+// @Router /v1/transactions/{lntxId} [get]
+func GetTransaction(c *gin.Context) {
+	lntx, err := lnpayClient.WithContext(c.Request.Context()).Transaction(c.Param("lntxId"))
+	if err != nil {
+		respondError(c, fromLNPayError("Error getting transaction", err))
+		return
+	}
+
+	respond(c, http.StatusOK, newLnTxResponse(lntx))
+}
+
+func newLnTxResponse(lntx lnpay.LnTx) LnTxResponse {
+	return LnTxResponse{
+		ID:              lntx.ID,
+		CreatedAt:       lntx.CreatedAt,
+		PaymentRequest:  lntx.PaymentRequest,
+		PaymentHash:     lntx.RHashDecoded,
+		Memo:            lntx.Memo,
+		DescriptionHash: lntx.DescriptionHash,
+		NumSatoshis:     lntx.NumSatoshis,
+		Expiry:          lntx.Expiry,
+		ExpiresAt:       lntx.ExpiresAt,
+		PaymentPreimage: lntx.PaymentPreimage,
+		Settled:         lntx.Settled == 1,
+		SettledAt:       lntx.SettledAt,
+		IsKeysend:       lntx.IsKeysend,
+		CustomRecords:   lntx.CustomRecords,
+	}
+}
+
+func newWalletTransactionResponse(wtx lnpay.Wtx) WalletTransactionResponse {
+	response := WalletTransactionResponse{
+		ID:          wtx.ID,
+		CreatedAt:   wtx.CreatedAt,
+		NumSatoshis: wtx.NumSatoshis,
+		WalletID:    wtx.Wal.ID,
+		Type:        wtx.WtxType.Name,
+		Layer:       wtx.WtxType.Layer,
+		UserLabel:   wtx.UserLabel,
+		PassThru:    wtx.PassThru,
+	}
+	if wtx.LnTx.ID != "" {
+		lntx := newLnTxResponse(wtx.LnTx)
+		response.LnTx = &lntx
+	}
+	return response
+}
@@ -0,0 +1,85 @@
+package diagnostics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+	"github.com/lnpay-wrapper-api-go/src/api/requestid"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/redact"
+)
+
+// UpstreamError is a failed call to lnpay. The error is redacted before it's kept.
+type UpstreamError struct {
+	Time      time.Time `json:"time"`
+	Endpoint  string    `json:"endpoint"`
+	Method    string    `json:"method"`
+	Status    int       `json:"status,omitempty"`
+	Wallet    string    `json:"wallet,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	Error     string    `json:"error"`
+}
+
+// UpstreamErrors keeps the last failed calls to lnpay in memory, the oldest are dropped once it's full.
+type UpstreamErrors struct {
+	mu     sync.Mutex
+	errors []UpstreamError
+	next   int
+	full   bool
+	total  int
+}
+
+func NewUpstreamErrors(size int) *UpstreamErrors {
+	if size < 1 {
+		size = 1
+	}
+	return &UpstreamErrors{errors: make([]UpstreamError, size)}
+}
+
+// Record keeps e, dropping the oldest error when full.
+func (u *UpstreamErrors) Record(e UpstreamError) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.errors[u.next] = e
+	u.next = (u.next + 1) % len(u.errors)
+	u.full = u.full || u.next == 0
+	u.total++
+}
+
+// Recent returns the errors kept, the newest first, and how many have been recorded since the start.
+func (u *UpstreamErrors) Recent() ([]UpstreamError, int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	count := u.next
+	if u.full {
+		count = len(u.errors)
+	}
+	recent := make([]UpstreamError, 0, count)
+	for i := 1; i <= count; i++ {
+		recent = append(recent, u.errors[(u.next-i+len(u.errors))%len(u.errors)])
+	}
+	return recent, u.total
+}
+
+// LNPayInterceptor records the calls to lnpay that fail, including the ones refused by the open circuit breaker.
+func (u *UpstreamErrors) LNPayInterceptor(call *lnpay.Call, next func() error) error {
+	err := next()
+	if err == nil {
+		return nil
+	}
+	e := UpstreamError{
+		Time:     time.Now(),
+		Endpoint: call.Endpoint,
+		Method:   call.Method,
+		Status:   call.Status,
+		Error:    redact.String(err.Error()),
+	}
+	if wallet, ok := call.Attributes[lnpay.AttrWallet].(string); ok {
+		e.Wallet = wallet
+	}
+	if id, ok := requestid.FromContext(call.Context); ok {
+		e.RequestID = id
+	}
+	u.Record(e)
+	return err
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+)
+
+func TestSubscriptionTokens(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tokens := NewSubscriptionTokens("secret", time.Hour)
+	tokens.now = func() time.Time { return now }
+	token, expiresAt := tokens.Issue("lntx_1")
+	if !expiresAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("expires at %v, want an hour later", expiresAt)
+	}
+
+	other := NewSubscriptionTokens("other secret", time.Hour)
+	other.now = tokens.now
+	tests := []struct {
+		name   string
+		tokens *SubscriptionTokens
+		lntxId string
+		token  string
+		at     time.Time
+		valid  bool
+	}{
+		{"issued", tokens, "lntx_1", token, now, true},
+		{"before expiring", tokens, "lntx_1", token, expiresAt.Add(-time.Second), true},
+		{"expired", tokens, "lntx_1", token, expiresAt, false},
+		{"other invoice", tokens, "lntx_2", token, now, false},
+		{"other secret", other, "lntx_1", token, now, false},
+		{"expiry changed", tokens, "lntx_1", "9999999999" + token[10:], now, false},
+		{"empty", tokens, "lntx_1", "", now, false},
+		{"no signature", tokens, "lntx_1", "9999999999", now, false},
+	}
+	for _, tt := range tests {
+		at := tt.at
+		tt.tokens.now = func() time.Time { return at }
+		if got := tt.tokens.Valid(tt.lntxId, tt.token); got != tt.valid {
+			t.Errorf("%s: valid %v, want %v", tt.name, got, tt.valid)
+		}
+	}
+}
+
+func TestRandomSecretTokensAreNotShared(t *testing.T) {
+	token, _ := NewSubscriptionTokens("", time.Hour).Issue("lntx_1")
+	if NewSubscriptionTokens("", time.Hour).Valid("lntx_1", token) {
+		t.Error("token accepted by an instance with another random secret")
+	}
+}
+
+func TestHubCapsSubscriptions(t *testing.T) {
+	fetches := 0
+	hub := NewInvoiceHub(func(lntxId string) (lnpay.LnTx, error) {
+		fetches++
+		if lntxId == "missing" {
+			return lnpay.LnTx{}, errors.New("not found")
+		}
+		return lnpay.LnTx{ID: lntxId}, nil
+	}, 2)
+
+	_, cancel1, err := hub.Subscribe("lntx_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := hub.Subscribe("missing"); err == nil {
+		t.Fatal("subscribed to a missing invoice")
+	}
+	_, cancel2, err := hub.Subscribe("lntx_1")
+	if err != nil {
+		t.Fatalf("failed subscriptions must not count: %v", err)
+	}
+	if _, _, err := hub.Subscribe("lntx_2"); err != ErrTooManySubscriptions {
+		t.Fatalf("got %v, want ErrTooManySubscriptions", err)
+	}
+	if fetches != 3 {
+		t.Errorf("lnpay called %d times, want 3 as the refused subscription isn't fetched", fetches)
+	}
+
+	cancel1()
+	cancel1()
+	if _, _, err := hub.Subscribe("lntx_2"); err != nil {
+		t.Errorf("cancelled subscriptions must free their slot: %v", err)
+	}
+	hub.Close()
+	cancel2()
+	if hub.subscriptions != 1 {
+		t.Errorf("%d subscriptions left, want 1", hub.subscriptions)
+	}
+}
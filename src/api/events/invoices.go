@@ -0,0 +1,247 @@
+/**
+* @author mnunez
+ */
+
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+	"github.com/lnpay-wrapper-api-go/src/api/metrics"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+)
+
+type InvoiceStatus string
+
+const (
+	StatusCreated InvoiceStatus = "created"
+	StatusSettled InvoiceStatus = "settled"
+	StatusExpired InvoiceStatus = "expired"
+)
+
+var (
+	ErrHubClosed            = errors.New("invoice hub is closed")
+	ErrTooManySubscriptions = errors.New("too many invoice subscriptions")
+)
+
+// subscriberBuffer is enough to hold every transition an invoice can go through.
+const subscriberBuffer = 4
+
+// InvoiceEvent is pushed to the subscribers every time the status of an invoice changes.
+type InvoiceEvent struct {
+	LnTxID      string        `json:"lntx_id"`
+	Status      InvoiceStatus `json:"status"`
+	NumSatoshis int64         `json:"num_satoshis"`
+	CreatedAt   int           `json:"created_at"`
+	ExpiresAt   int           `json:"expires_at"`
+	SettledAt   int           `json:"settled_at,omitempty"`
+	Timestamp   int64         `json:"timestamp"`
+}
+
+// Final reports whether the invoice can't change its status anymore.
+func (e InvoiceEvent) Final() bool {
+	return e.Status != StatusCreated
+}
+
+// Fetcher returns the current state of a lightning transaction.
+type Fetcher func(lntxId string) (lnpay.LnTx, error)
+
+type watch struct {
+	last        InvoiceEvent
+	subscribers map[chan InvoiceEvent]struct{}
+}
+
+// InvoiceHub keeps track of the invoices somebody is waiting on and fans out their status changes.
+// At most maxSubscriptions streams are open at once, counting the ones still fetching their invoice.
+type InvoiceHub struct {
+	fetch            Fetcher
+	maxSubscriptions int
+	mu               sync.Mutex
+	watched          map[string]*watch
+	subscriptions    int
+	closed           bool
+}
+
+func NewInvoiceHub(fetch Fetcher, maxSubscriptions int) *InvoiceHub {
+	return &InvoiceHub{
+		fetch:            fetch,
+		maxSubscriptions: maxSubscriptions,
+		watched:          make(map[string]*watch),
+	}
+}
+
+// Subscribe starts watching an invoice. The returned channel receives the current status right away
+// and then every change until the invoice is settled or expired. cancel must be called once done.
+// ErrTooManySubscriptions is returned, without calling lnpay, when the hub is full.
+func (h *InvoiceHub) Subscribe(lntxId string) (events <-chan InvoiceEvent, cancel func(), err error) {
+	h.mu.Lock()
+	if h.subscriptions >= h.maxSubscriptions {
+		h.mu.Unlock()
+		return nil, nil, ErrTooManySubscriptions
+	}
+	h.subscriptions++
+	h.mu.Unlock()
+
+	lntx, err := h.fetch(lntxId)
+	if err != nil {
+		h.unsubscribed()
+		return nil, nil, err
+	}
+
+	ch := make(chan InvoiceEvent, subscriberBuffer)
+	h.mu.Lock()
+	if h.closed {
+		h.subscriptions--
+		h.mu.Unlock()
+		return nil, nil, ErrHubClosed
+	}
+	w, ok := h.watched[lntxId]
+	if !ok {
+		w = &watch{subscribers: make(map[chan InvoiceEvent]struct{})}
+		h.watched[lntxId] = w
+	}
+	h.publishLocked(w, newInvoiceEvent(lntxId, lntx, time.Now()))
+	ch <- w.last
+	w.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	cancel = func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			h.subscriptions--
+			if _, ok := w.subscribers[ch]; !ok {
+				// already closed by Close
+				return
+			}
+			delete(w.subscribers, ch)
+			if len(w.subscribers) == 0 && h.watched[lntxId] == w {
+				delete(h.watched, lntxId)
+			}
+			close(ch)
+		})
+	}
+	return ch, cancel, nil
+}
+
+// Refresh fetches the invoice from lnpay and publishes its status if anybody is watching it.
+func (h *InvoiceHub) Refresh(lntxId string) error {
+	if !h.isWatched(lntxId) {
+		return nil
+	}
+	lntx, err := h.fetch(lntxId)
+	if err != nil {
+		return err
+	}
+	h.Publish(newInvoiceEvent(lntxId, lntx, time.Now()))
+	return nil
+}
+
+// Publish notifies the subscribers of an invoice when its status changed.
+func (h *InvoiceHub) Publish(event InvoiceEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if w, ok := h.watched[event.LnTxID]; ok {
+		h.publishLocked(w, event)
+	}
+}
+
+// Pending returns the ids of the watched invoices that are neither settled nor expired.
+func (h *InvoiceHub) Pending() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ids := make([]string, 0, len(h.watched))
+	for id, w := range h.watched {
+		if !w.last.Final() {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Poll refreshes the pending invoices every interval until ctx is done.
+// It is the fallback for the webhooks lnpay fails to deliver.
+func (h *InvoiceHub) Poll(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, id := range h.Pending() {
+				if err := h.Refresh(id); err != nil {
+					logger.Component(logger.ComponentEvents).Error("Error polling invoice "+id, err, "lntx_id:"+id)
+				}
+			}
+		}
+	}
+}
+
+// Close stops watching every invoice, closing the subscribers channels so the streams end.
+func (h *InvoiceHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for id, w := range h.watched {
+		for ch := range w.subscribers {
+			delete(w.subscribers, ch)
+			close(ch)
+		}
+		delete(h.watched, id)
+	}
+}
+
+func (h *InvoiceHub) unsubscribed() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscriptions--
+}
+
+func (h *InvoiceHub) isWatched(lntxId string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, ok := h.watched[lntxId]
+	return ok
+}
+
+func (h *InvoiceHub) publishLocked(w *watch, event InvoiceEvent) {
+	if w.last.Status == event.Status || w.last.Final() {
+		return
+	}
+	if event.Status == StatusSettled && w.last.Status != "" && event.SettledAt >= event.CreatedAt {
+		// only the invoices seen pending are measured, not the ones already settled when followed
+		metrics.Settlement(time.Duration(event.SettledAt-event.CreatedAt) * time.Second)
+	}
+	w.last = event
+	for ch := range w.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func newInvoiceEvent(lntxId string, lntx lnpay.LnTx, now time.Time) InvoiceEvent {
+	event := InvoiceEvent{
+		LnTxID:      lntxId,
+		Status:      StatusCreated,
+		NumSatoshis: lntx.NumSatoshis,
+		CreatedAt:   lntx.CreatedAt,
+		ExpiresAt:   lntx.ExpiresAt,
+		SettledAt:   lntx.SettledAt,
+		Timestamp:   now.Unix(),
+	}
+	switch {
+	case lntx.Settled == 1:
+		event.Status = StatusSettled
+	case lntx.ExpiresAt > 0 && now.Unix() > int64(lntx.ExpiresAt):
+		event.Status = StatusExpired
+	}
+	return event
+}
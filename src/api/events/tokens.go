@@ -0,0 +1,58 @@
+package events
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SubscriptionTokens signs the tokens that allow following the events of an invoice without an api key.
+// CreateInvoice hands one out with every invoice, for the checkout frontend to open the stream.
+type SubscriptionTokens struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewSubscriptionTokens signs with secret tokens valid for ttl. When secret is empty a random one is
+// generated, so the tokens are only accepted by this instance until it restarts.
+func NewSubscriptionTokens(secret string, ttl time.Duration) *SubscriptionTokens {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic("events: can't generate the subscription token secret: " + err.Error())
+		}
+	}
+	return &SubscriptionTokens{secret: key, ttl: ttl, now: time.Now}
+}
+
+// Issue returns a token to follow the invoice and when it expires.
+func (t *SubscriptionTokens) Issue(lntxId string) (token string, expiresAt time.Time) {
+	expiresAt = t.now().Add(t.ttl).Truncate(time.Second)
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expiry + "." + t.sign(lntxId, expiry), expiresAt
+}
+
+// Valid reports whether token was issued for the invoice and hasn't expired.
+func (t *SubscriptionTokens) Valid(lntxId string, token string) bool {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	expiry, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || t.now().Unix() >= expiry {
+		return false
+	}
+	return hmac.Equal([]byte(parts[1]), []byte(t.sign(lntxId, parts[0])))
+}
+
+func (t *SubscriptionTokens) sign(lntxId string, expiry string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(lntxId + "." + expiry))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,88 @@
+/**
+* @author mnunez
+ */
+
+package events
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/lnpay-wrapper-api-go/src/api/metrics"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+)
+
+var ErrQueueFull = errors.New("webhook queue is full")
+var ErrQueueClosed = errors.New("webhook queue is closed")
+
+// WebhookQueue processes the lnpay webhooks asynchronously so the receiver can answer right away.
+// Webhooks are only taken as a hint: the invoice is fetched again from lnpay before publishing
+// anything, so a forged webhook can't mark an invoice as paid.
+type WebhookQueue struct {
+	hub    *InvoiceHub
+	queue  chan string
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewWebhookQueue creates a queue holding up to size webhooks and starts its workers.
+func NewWebhookQueue(hub *InvoiceHub, size int, workers int) *WebhookQueue {
+	q := &WebhookQueue{
+		hub:   hub,
+		queue: make(chan string, size),
+	}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue schedules a refresh of the given lightning transaction.
+func (q *WebhookQueue) Enqueue(lntxId string) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case q.queue <- lntxId:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Depth returns how many webhooks are waiting to be processed.
+func (q *WebhookQueue) Depth() int {
+	return len(q.queue)
+}
+
+// Capacity returns how many webhooks the queue can hold.
+func (q *WebhookQueue) Capacity() int {
+	return cap(q.queue)
+}
+
+// Close stops accepting webhooks and waits until the queued ones are processed.
+func (q *WebhookQueue) Close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.queue)
+	}
+	q.mu.Unlock()
+	q.wg.Wait()
+}
+
+func (q *WebhookQueue) work() {
+	defer q.wg.Done()
+	for lntxId := range q.queue {
+		if err := q.hub.Refresh(lntxId); err != nil {
+			logger.Component(logger.ComponentEvents).Error("Error processing webhook for "+lntxId, err, "lntx_id:"+lntxId)
+			metrics.Webhook("processed", "failed")
+			continue
+		}
+		metrics.Webhook("processed", "refreshed")
+	}
+}
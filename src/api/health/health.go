@@ -0,0 +1,149 @@
+/**
+* @author mnunez
+ */
+
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// Result is the outcome of a check.
+type Result struct {
+	Status    Status      `json:"status"`
+	Error     string      `json:"error,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+	CheckedAt time.Time   `json:"checked_at"`
+	Cached    bool        `json:"cached,omitempty"`
+}
+
+func Up(details interface{}) Result {
+	return Result{Status: StatusUp, Details: details}
+}
+
+func Degraded(reason string, details interface{}) Result {
+	return Result{Status: StatusDegraded, Error: reason, Details: details}
+}
+
+func Down(err error, details interface{}) Result {
+	return Result{Status: StatusDown, Error: err.Error(), Details: details}
+}
+
+// Check probes one of the dependencies of the api.
+type Check struct {
+	Name string
+	// Critical checks make the api not ready when they are down, the others only degrade it.
+	Critical bool
+	// CacheTTL is how long a result is reused, so the probes don't hit the dependency every time.
+	CacheTTL time.Duration
+	Run      func(ctx context.Context) Result
+}
+
+// Report is the status of the api and of every check.
+type Report struct {
+	Status    Status            `json:"status"`
+	Checks    map[string]Result `json:"checks"`
+	CheckedAt time.Time         `json:"checked_at"`
+}
+
+// Ready reports whether the api can take traffic.
+func (r Report) Ready() bool {
+	return r.Status != StatusDown
+}
+
+// Checker runs the checks, each one bounded by timeout.
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+	mu      sync.Mutex
+	cache   map[string]Result
+}
+
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		checks:  checks,
+		timeout: timeout,
+		cache:   make(map[string]Result),
+	}
+}
+
+// Report runs the checks concurrently, reusing the cached results still fresh.
+func (c *Checker) Report(ctx context.Context) Report {
+	now := time.Now().UTC()
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		if result, ok := c.cached(check, now); ok {
+			results[i] = result
+			continue
+		}
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(c.checks)), CheckedAt: now}
+	for i, check := range c.checks {
+		result := results[i]
+		report.Checks[check.Name] = result
+		switch {
+		case result.Status == StatusDown && check.Critical:
+			report.Status = StatusDown
+		case result.Status != StatusUp && report.Status == StatusUp:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	done := make(chan Result, 1)
+	go func() {
+		done <- check.Run(ctx)
+	}()
+
+	var result Result
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result = Down(ctx.Err(), nil)
+	}
+	result.CheckedAt = time.Now().UTC()
+
+	if check.CacheTTL > 0 {
+		c.mu.Lock()
+		c.cache[check.Name] = result
+		c.mu.Unlock()
+	}
+	return result
+}
+
+func (c *Checker) cached(check Check, now time.Time) (Result, bool) {
+	if check.CacheTTL <= 0 {
+		return Result{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result, ok := c.cache[check.Name]
+	if !ok || now.Sub(result.CheckedAt) >= check.CacheTTL {
+		return Result{}, false
+	}
+	result.Cached = true
+	return result, true
+}
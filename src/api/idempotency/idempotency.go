@@ -0,0 +1,36 @@
+/**
+* @author mnunez
+ */
+
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var ErrNotFound = errors.New("idempotency key not found")
+
+// Record is what we keep about a request made with an Idempotency-Key.
+type Record struct {
+	// Fingerprint identifies the request the key was first used with.
+	Fingerprint string      `json:"fingerprint"`
+	Completed   bool        `json:"completed"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// Store keeps the idempotency records until their ttl expires.
+type Store interface {
+	// Begin saves an in progress record for key unless there is one already.
+	// When the key is taken it returns the existing record and false.
+	Begin(ctx context.Context, key string, fingerprint string, ttl time.Duration) (Record, bool, error)
+	// Complete replaces the in progress record with the final response.
+	Complete(ctx context.Context, key string, record Record, ttl time.Duration) error
+	// Release deletes the record so the request can be retried.
+	Release(ctx context.Context, key string) error
+	Get(ctx context.Context, key string) (Record, error)
+}
@@ -0,0 +1,71 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	record  Record
+	expires time.Time
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]entry
+	now     func() time.Time
+}
+
+// NewMemoryStore returns a Store keeping the records in the process memory.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		entries: make(map[string]entry),
+		now:     time.Now,
+	}
+}
+
+func (s *memoryStore) Begin(_ context.Context, key string, fingerprint string, ttl time.Duration) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+	if e, ok := s.entries[key]; ok {
+		return e.record, false, nil
+	}
+	record := Record{Fingerprint: fingerprint}
+	s.entries[key] = entry{record: record, expires: now.Add(ttl)}
+	return record, true, nil
+}
+
+func (s *memoryStore) Complete(_ context.Context, key string, record Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = entry{record: record, expires: s.now().Add(ttl)}
+	return nil
+}
+
+func (s *memoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *memoryStore) Get(_ context.Context, key string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok || !s.now().Before(e.expires) {
+		return Record{}, ErrNotFound
+	}
+	return e.record, nil
+}
+
+func (s *memoryStore) sweep(now time.Time) {
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
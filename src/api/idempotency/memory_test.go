@@ -0,0 +1,50 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore().(*memoryStore)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, acquired, _ := store.Begin(ctx, "k", "fp", time.Minute); !acquired {
+		t.Fatal("first Begin didn't acquire the key")
+	}
+	record, acquired, _ := store.Begin(ctx, "k", "other", time.Minute)
+	if acquired || record.Fingerprint != "fp" || record.Completed {
+		t.Fatalf("second Begin got %+v acquired %v, want the in progress record", record, acquired)
+	}
+
+	done := Record{Fingerprint: "fp", Completed: true, Status: 201, Body: []byte("{}")}
+	if err := store.Complete(ctx, "k", done, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if record, err := store.Get(ctx, "k"); err != nil || !record.Completed || record.Status != 201 {
+		t.Fatalf("Get got %+v %v, want the completed record", record, err)
+	}
+
+	// the completed record outlives the lock ttl
+	now = now.Add(time.Minute)
+	if _, err := store.Get(ctx, "k"); err != nil {
+		t.Errorf("completed record expired with the lock ttl: %v", err)
+	}
+	now = now.Add(time.Hour)
+	if _, err := store.Get(ctx, "k"); err != ErrNotFound {
+		t.Errorf("Get after the ttl got %v, want ErrNotFound", err)
+	}
+	if _, acquired, _ := store.Begin(ctx, "k", "fp", time.Minute); !acquired {
+		t.Error("Begin after the ttl didn't acquire the key")
+	}
+
+	if err := store.Release(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, "k"); err != ErrNotFound {
+		t.Errorf("Get after Release got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,69 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// beginAttempts bounds how many times Begin tries again when the key expires between reserving and reading it.
+const beginAttempts = 3
+
+type redisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore returns a Store keeping the records in redis, shared by every instance of the api.
+func NewRedisStore(client redis.UniversalClient) Store {
+	return &redisStore{client: client}
+}
+
+func (s *redisStore) Begin(ctx context.Context, key string, fingerprint string, ttl time.Duration) (Record, bool, error) {
+	record := Record{Fingerprint: fingerprint}
+	value, err := json.Marshal(record)
+	if err != nil {
+		return Record{}, false, err
+	}
+	for attempt := 1; ; attempt++ {
+		acquired, err := s.client.SetNX(ctx, key, value, ttl).Result()
+		if err != nil {
+			return Record{}, false, err
+		}
+		if acquired {
+			return record, true, nil
+		}
+		existing, err := s.Get(ctx, key)
+		if err == ErrNotFound && attempt < beginAttempts {
+			// the key expired or was released after SetNX, try to reserve it again
+			continue
+		}
+		return existing, false, err
+	}
+}
+
+func (s *redisStore) Complete(ctx context.Context, key string, record Record, ttl time.Duration) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (s *redisStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, key).Err()
+}
+
+func (s *redisStore) Get(ctx context.Context, key string) (Record, error) {
+	value, err := s.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return Record{}, ErrNotFound
+	}
+	if err != nil {
+		return Record{}, err
+	}
+	var record Record
+	err = json.Unmarshal(value, &record)
+	return record, err
+}
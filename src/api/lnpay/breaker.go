@@ -0,0 +1,128 @@
+package lnpay
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("lnpay circuit breaker is open")
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// Breaker stops calling lnpay after Threshold consecutive failures, failing fast until Cooldown
+// elapses. Then a single request is let through: the circuit closes again if it succeeds.
+// Only network errors and 5xx answers count as failures.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	lastError error
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{Threshold: threshold, Cooldown: cooldown}
+}
+
+// BreakerStatus is a snapshot of the breaker.
+type BreakerStatus struct {
+	State     BreakerState `json:"state"`
+	Failures  int          `json:"failures"`
+	OpenedAt  *time.Time   `json:"opened_at,omitempty"`
+	LastError string       `json:"last_error,omitempty"`
+}
+
+// Status returns the current state of the breaker.
+func (b *Breaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := BreakerStatus{State: b.stateLocked(time.Now()), Failures: b.failures}
+	if status.State != BreakerClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	if b.lastError != nil {
+		status.LastError = b.lastError.Error()
+	}
+	return status
+}
+
+// Do calls fn unless the circuit is open, in which case it returns ErrCircuitOpen.
+func (b *Breaker) Do(fn func() error) error {
+	if b == nil || b.Threshold <= 0 {
+		return fn()
+	}
+	if !b.allow() {
+		return ErrCircuitOpen
+	}
+	err := fn()
+	b.record(err)
+	return err
+}
+
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.stateLocked(time.Now()) {
+	case BreakerClosed:
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *Breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !isFailure(err) {
+		b.failures = 0
+		b.openedAt = time.Time{}
+		return
+	}
+	b.failures++
+	b.lastError = err
+	if b.failures >= b.Threshold {
+		b.openedAt = time.Now()
+	}
+}
+
+func (b *Breaker) stateLocked(now time.Time) BreakerState {
+	switch {
+	case b.openedAt.IsZero():
+		return BreakerClosed
+	case now.Sub(b.openedAt) >= b.Cooldown:
+		return BreakerHalfOpen
+	default:
+		return BreakerOpen
+	}
+}
+
+func isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var reqErr Error
+	if errors.As(err, &reqErr) {
+		return reqErr.Status >= http.StatusInternalServerError
+	}
+	var validationErrs ValidationErrors
+	return !errors.As(err, &validationErrs)
+}
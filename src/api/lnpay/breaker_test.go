@@ -0,0 +1,91 @@
+package lnpay
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var (
+	errNetwork  = errors.New("connection refused")
+	errUpstream = Error{Message: "internal error", Status: 500}
+	errNotFound = Error{Message: "not found", Status: 404}
+	errInvalid  = ValidationErrors{{Field: "num_satoshis", Code: "gt", Message: "must be greater than zero"}}
+)
+
+func TestBreakerCountsFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		errs []error
+		open bool
+	}{
+		{"network errors", []error{errNetwork, errNetwork, errNetwork}, true},
+		{"5xx answers", []error{errUpstream, errNetwork, errUpstream}, true},
+		{"4xx answers", []error{errNotFound, errNotFound, errNotFound}, false},
+		{"invalid params", []error{errInvalid, errInvalid, errInvalid}, false},
+		{"success resets", []error{errNetwork, errNetwork, nil, errNetwork, errNetwork}, false},
+		{"below threshold", []error{errNetwork, errNetwork}, false},
+	}
+	for _, tt := range tests {
+		b := NewBreaker(3, time.Hour)
+		for _, err := range tt.errs {
+			err := err
+			b.Do(func() error { return err })
+		}
+		calls := 0
+		err := b.Do(func() error { calls++; return nil })
+		if open := err == ErrCircuitOpen; open != tt.open {
+			t.Errorf("%s: open %v, want %v", tt.name, open, tt.open)
+		}
+		if tt.open && calls != 0 {
+			t.Errorf("%s: called lnpay with the circuit open", tt.name)
+		}
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b := NewBreaker(1, 20*time.Millisecond)
+	b.Do(func() error { return errNetwork })
+	if status := b.Status(); status.State != BreakerOpen || status.LastError != errNetwork.Error() || status.OpenedAt == nil {
+		t.Fatalf("status %+v, want open with the last error", status)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if state := b.Status().State; state != BreakerHalfOpen {
+		t.Fatalf("state %s after the cooldown, want half open", state)
+	}
+	// a single probe is let through, it failing opens the circuit again
+	probe := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- b.Do(func() error { <-probe; return errNetwork })
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := b.Do(func() error { return nil }); err != ErrCircuitOpen {
+		t.Errorf("second request during the probe got %v, want ErrCircuitOpen", err)
+	}
+	close(probe)
+	<-done
+	if state := b.Status().State; state != BreakerOpen {
+		t.Fatalf("state %s after a failed probe, want open", state)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if err := b.Do(func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if status := b.Status(); status.State != BreakerClosed || status.Failures != 0 {
+		t.Errorf("status %+v after a successful probe, want closed", status)
+	}
+}
+
+func TestDisabledBreaker(t *testing.T) {
+	var nilBreaker *Breaker
+	for _, b := range []*Breaker{nilBreaker, NewBreaker(0, time.Hour)} {
+		for i := 0; i < 5; i++ {
+			if err := b.Do(func() error { return errNetwork }); err != errNetwork {
+				t.Fatalf("got %v, want every call made", err)
+			}
+		}
+	}
+}
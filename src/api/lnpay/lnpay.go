@@ -1,11 +1,17 @@
 package lnpay
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/imroc/req"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/redact"
 )
 
 const (
@@ -14,10 +20,93 @@ const (
 )
 
 type Client struct {
-	header req.Header
+	header       req.Header
+	req          *req.Req
+	baseURL      string
+	node         string
+	retry        Retry
+	breaker      *Breaker
+	interceptors []Interceptor
+	onLockWait   func(ctx context.Context, operation string, wait time.Duration)
+	ctx          context.Context
 }
 
-var transactionLock sync.Mutex
+// ErrInvalidAnswer is returned when lnpay answers successfully with something that can't be used.
+var ErrInvalidAnswer = errors.New("invalid answer from lnpay")
+
+// Attributes set on the calls moving funds.
+const (
+	AttrWallet   = "lnpay.wallet"
+	AttrAmount   = "lnpay.amount_sats"
+	AttrLockWait = "lnpay.lock_wait_ms"
+)
+
+// Call describes a call to lnpay as seen by the interceptors.
+type Call struct {
+	Context context.Context
+	// Endpoint names the call without the ids of the url, e.g. wallet.withdraw.
+	Endpoint string
+	Method   string
+	URL      string
+	// Header is sent with the request, the interceptors may add their own headers.
+	Header req.Header
+	// Attributes describe the call, e.g. the wallet and the amount of the operations moving funds.
+	Attributes map[string]interface{}
+	// Status is the status lnpay answered with, 0 when it didn't answer.
+	Status int
+}
+
+// Retry retries the calls reading from lnpay that fail with a network error or a 5xx answer, waiting Backoff
+// times the attempt between them. The calls moving funds are never retried.
+type Retry struct {
+	Attempts int
+	Backoff  time.Duration
+}
+
+// Interceptor wraps the calls to lnpay, e.g. to instrument them. It must call next to perform the call.
+type Interceptor func(call *Call, next func() error) error
+
+// transactionLocks serializes the operations moving funds of each wallet, by the wallet url. The operations of
+// different wallets don't wait for each other.
+var transactionLocks = struct {
+	sync.Mutex
+	wallets map[string]*walletLock
+}{wallets: make(map[string]*walletLock)}
+
+// walletLock is the lock of a wallet along with the number of operations holding or waiting for it, it's
+// dropped once none is left.
+type walletLock struct {
+	sync.Mutex
+	operations int
+}
+
+// lockTransaction locks the operations moving funds of the wallet, returning the function releasing the lock
+// and how long operation waited.
+func (w *Wallet) lockTransaction(operation string) (func(), time.Duration) {
+	start := time.Now()
+	transactionLocks.Lock()
+	lock, ok := transactionLocks.wallets[w.BaseUrl]
+	if !ok {
+		lock = &walletLock{}
+		transactionLocks.wallets[w.BaseUrl] = lock
+	}
+	lock.operations++
+	transactionLocks.Unlock()
+
+	lock.Lock()
+	wait := time.Since(start)
+	if w.onLockWait != nil {
+		w.onLockWait(w.context(), operation, wait)
+	}
+	return func() {
+		lock.Unlock()
+		transactionLocks.Lock()
+		if lock.operations--; lock.operations == 0 {
+			delete(transactionLocks.wallets, w.BaseUrl)
+		}
+		transactionLocks.Unlock()
+	}, wait
+}
 
 // NewClient is the first function you must call. Pass your main API key here.
 // It will return a client you can later use to access wallets and transactions.
@@ -29,56 +118,188 @@ func NewClient(key string) *Client {
 			"Content-Type": "application/json",
 			"Accept":       "application/json",
 		},
+		req:     req.New(),
+		baseURL: BASE_URL,
+		node:    "default",
+		breaker: NewBreaker(5, 30*time.Second),
 	}
 }
 
-// Transaction
-func (c *Client) Transaction(lntxId string) (lnTx LnTx, err error) {
-	resp, err := req.Get(BASE_URL+"/lntx/"+lntxId, c.header)
-	if err != nil {
-		return
+// SetBaseURL changes the url of the lnpay api, https://api.lnpay.co/v1 by default.
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+// SetNode changes the node queried for routes and invoices, the default node of the account by default.
+func (c *Client) SetNode(node string) {
+	c.node = node
+}
+
+// SetTimeout bounds the calls to lnpay, answer included. A payment timing out may still be completed by lnpay.
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.req.SetTimeout(timeout)
+}
+
+// SetRetry sets how the calls reading from lnpay are retried, they aren't by default.
+func (c *Client) SetRetry(retry Retry) {
+	c.retry = retry
+}
+
+// Use adds interceptors wrapping every call to lnpay, the first one added is the outermost.
+func (c *Client) Use(interceptors ...Interceptor) {
+	c.interceptors = append(c.interceptors, interceptors...)
+}
+
+// OnLockWait sets fn to be called with the time the operations moving funds waited for the transaction lock.
+func (c *Client) OnLockWait(fn func(ctx context.Context, operation string, wait time.Duration)) {
+	c.onLockWait = fn
+}
+
+// WithContext returns a copy of the client whose calls carry ctx, handed to the interceptors to
+// correlate the calls with the request being served. ctx doesn't cancel the calls: a payment
+// already sent must get its answer even when the caller goes away.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// SetBreaker replaces the circuit breaker guarding the calls to lnpay. A nil breaker disables it.
+func (c *Client) SetBreaker(breaker *Breaker) {
+	c.breaker = breaker
+}
+
+// Breaker returns the circuit breaker guarding the calls to lnpay.
+func (c *Client) Breaker() *Breaker {
+	return c.breaker
+}
+
+// Ping checks that lnpay is reachable and accepts the api key. It bypasses the circuit breaker
+// so it reports the actual state of lnpay even while the circuit is open.
+func (c *Client) Ping(ctx context.Context) error {
+	call := c.newCall("wallets.list", http.MethodGet, c.baseURL+"/wallets?per-page=1")
+	call.Context = ctx
+	return c.intercept(call, func() error {
+		_, err := c.do(call, ctx)
+		return err
+	})
+}
+
+func (c *Client) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
 	}
+	return c.ctx
+}
 
-	if resp.Response().StatusCode >= 300 {
-		var reqErr Error
-		resp.ToJSON(&reqErr)
-		err = reqErr
-		return
+func (c *Client) newCall(endpoint, method, rawurl string) *Call {
+	header := make(req.Header, len(c.header))
+	for k, v := range c.header {
+		header[k] = v
+	}
+	return &Call{
+		Context:    c.context(),
+		Endpoint:   endpoint,
+		Method:     method,
+		URL:        rawurl,
+		Header:     header,
+		Attributes: map[string]interface{}{},
 	}
+}
+
+// amounter is implemented by the answers moving funds, to report their amount.
+type amounter interface {
+	amount() int64
+}
 
-	err = resp.ToJSON(&lnTx)
+// send performs the call through the interceptors and the circuit breaker and decodes the answer into out.
+func (c *Client) send(call *Call, out interface{}, v ...interface{}) (header http.Header, err error) {
+	err = c.intercept(call, func() error {
+		var resp *req.Resp
+		err := c.retrying(call, func() error {
+			return c.breaker.Do(func() (doErr error) {
+				resp, doErr = c.do(call, v...)
+				return doErr
+			})
+		})
+		if err != nil {
+			return err
+		}
+		header = resp.Response().Header
+		if err := resp.ToJSON(out); err != nil {
+			return err
+		}
+		if a, ok := out.(amounter); ok {
+			if _, set := call.Attributes[AttrAmount]; !set {
+				call.Attributes[AttrAmount] = a.amount()
+			}
+		}
+		return nil
+	})
 	return
 }
-func (c *Client) QueryRoutes(pubKey, amt string) (routes QueryRoutes, err error) {
-	resp, err := req.Get(fmt.Sprintf("%s/node/default/payments/queryroutes?pub_key=%s&amt=%s", BASE_URL, pubKey, amt), c.header)
-	if err != nil {
-		return
-	}
 
-	if resp.Response().StatusCode >= 300 {
-		var reqErr Error
-		resp.ToJSON(&reqErr)
-		err = reqErr
-		return
+// retrying performs the call, retrying it as set by SetRetry when it only reads from lnpay.
+func (c *Client) retrying(call *Call, perform func() error) error {
+	attempts := 1
+	if call.Method == http.MethodGet {
+		attempts += c.retry.Attempts
 	}
+	for attempt := 1; ; attempt++ {
+		err := perform()
+		if err == nil || attempt >= attempts || errors.Is(err, ErrCircuitOpen) || !isFailure(err) {
+			return err
+		}
+		time.Sleep(c.retry.Backoff * time.Duration(attempt))
+	}
+}
 
-	err = resp.ToJSON(&routes)
-	return
+// intercept runs perform wrapped by the interceptors.
+func (c *Client) intercept(call *Call, perform func() error) error {
+	next := perform
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := c.interceptors[i], next
+		next = func() error { return interceptor(call, inner) }
+	}
+	return next()
 }
-func (c *Client) DecodeInvoice(paymentRequest string) (invoice Invoice, err error) {
-	resp, err := req.Get(fmt.Sprintf("%s/node/default/payments/decodeinvoice?payment_request=%s", BASE_URL, paymentRequest), c.header)
+
+// do performs the request, turning the error answers into an Error.
+func (c *Client) do(call *Call, v ...interface{}) (*req.Resp, error) {
+	resp, err := c.req.Do(call.Method, call.URL, append([]interface{}{call.Header}, v...)...)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	if resp.Response().StatusCode >= 300 {
+	call.Status = resp.Response().StatusCode
+	if call.Status >= 300 {
 		var reqErr Error
 		resp.ToJSON(&reqErr)
-		err = reqErr
-		return
+		if reqErr.Status == 0 {
+			reqErr.Status = call.Status
+		}
+		return nil, reqErr
 	}
+	return resp, nil
+}
 
-	err = resp.ToJSON(&invoice)
+// Transaction
+func (c *Client) Transaction(lntxId string) (lnTx LnTx, err error) {
+	_, err = c.send(c.newCall("lntx.get", http.MethodGet, c.baseURL+"/lntx/"+url.PathEscape(lntxId)), &lnTx)
+	return
+}
+
+// QueryRoutes returns the routes the node can use to pay amt satoshis to the node with the given public key.
+func (c *Client) QueryRoutes(pubKey, amt string) (routes QueryRoutes, err error) {
+	query := url.Values{"pub_key": {pubKey}, "amt": {amt}}
+	_, err = c.send(c.newCall("node.queryroutes", http.MethodGet, c.baseURL+"/node/"+url.PathEscape(c.node)+"/payments/queryroutes?"+query.Encode()), &routes)
+	return
+}
+
+// DecodeInvoice decodes a BOLT11 payment request.
+func (c *Client) DecodeInvoice(paymentRequest string) (invoice Invoice, err error) {
+	query := url.Values{"payment_request": {paymentRequest}}
+	_, err = c.send(c.newCall("node.decodeinvoice", http.MethodGet, c.baseURL+"/node/"+url.PathEscape(c.node)+"/payments/decodeinvoice?"+query.Encode()), &invoice)
 	return
 }
 
@@ -86,23 +307,17 @@ func (c *Client) DecodeInvoice(paymentRequest string) (invoice Invoice, err erro
 // It will return the wallet object which you can use to create invoices and payments.
 // https://docs.lnpay.co/wallet/create-wallet
 func (c *Client) CreateWallet(label string) (wal Wallet, err error) {
-	resp, err := req.Post(BASE_URL+"/wallet", c.header, req.BodyJSON(struct {
+	_, err = c.send(c.newCall("wallet.create", http.MethodPost, c.baseURL+"/wallet"), &wal, req.BodyJSON(struct {
 		UserLabel string `json:"user_label"`
 	}{label}))
 	if err != nil {
 		return
 	}
-
-	if resp.Response().StatusCode >= 300 {
-		var reqErr Error
-		resp.ToJSON(&reqErr)
-		err = reqErr
-		return
+	if len(wal.AccessKeys.WalletAdmin) == 0 {
+		return wal, fmt.Errorf("%w: wallet %s has no admin key", ErrInvalidAnswer, wal.ID)
 	}
-
-	err = resp.ToJSON(&wal)
 	wal.Client = c
-	wal.BaseUrl = BASE_URL + "/wallet/" + wal.AccessKeys.WalletAdmin[0]
+	wal.BaseUrl = c.baseURL + "/wallet/" + wal.AccessKeys.WalletAdmin[0]
 	return
 }
 
@@ -114,28 +329,40 @@ func (c *Client) Wallet(key string) *Wallet {
 		Client:     c,
 		StatusType: StatusType{},
 		AccessKeys: AccessKeys{},
-		BaseUrl:    BASE_URL + "/wallet/" + key,
+		BaseUrl:    c.baseURL + "/wallet/" + key,
 	}
 }
 
 type WalletStatusType map[string]string
 
-// Details returns basic information about a wallet, such as its id, label or balance.
-// https://docs.lnpay.co/wallet/get-balance
-func (w *Wallet) Details() (wal Wallet, err error) {
-	resp, err := req.Get(w.BaseUrl, w.header)
-	if err != nil {
-		return
-	}
+// newCall describes a call to path, relative to the wallet url.
+func (w *Wallet) newCall(endpoint, method, path string) *Call {
+	call := w.Client.newCall(endpoint, method, w.BaseUrl+path)
+	call.Attributes[AttrWallet] = w.identifier()
+	return call
+}
 
-	if resp.Response().StatusCode >= 300 {
-		var reqErr Error
-		resp.ToJSON(&reqErr)
-		err = reqErr
-		return
+// identifier identifies the wallet in the calls without exposing its access keys.
+func (w *Wallet) identifier() string {
+	if w.ID != "" {
+		return w.ID
+	}
+	key := strings.TrimPrefix(w.BaseUrl, w.Client.baseURL+"/wallet/")
+	if strings.HasPrefix(key, "wal_") {
+		return key
 	}
+	return MaskKey(key)
+}
 
-	err = resp.ToJSON(&wal)
+// MaskKey hides all but the prefix and the last characters of an access key.
+func MaskKey(key string) string {
+	return redact.Key(key)
+}
+
+// Details returns basic information about a wallet, such as its id, label or balance.
+// https://docs.lnpay.co/wallet/get-balance
+func (w *Wallet) Details() (wal Wallet, err error) {
+	_, err = w.send(w.newCall("wallet.get", http.MethodGet, ""), &wal)
 	return
 }
 func (w *Wallet) UpdateBalance() error {
@@ -149,22 +376,14 @@ func (w *Wallet) UpdateBalance() error {
 	return nil
 }
 
+// TransactionsPerPage is how many transactions each page returned by Transactions holds.
+const TransactionsPerPage = 10
+
 // Transactions returns a list of the transactions associated with the wallet.
 // https://docs.lnpay.co/wallet/get-transactions
 func (w *Wallet) Transactions(page int) (txs []Wtx, header http.Header, err error) {
-	resp, err := req.Get(w.BaseUrl+fmt.Sprintf("/transactions?per-page=10&page=%d", page), w.header)
-	if err != nil {
-		return
-	}
-
-	if resp.Response().StatusCode >= 300 {
-		var reqErr Error
-		resp.ToJSON(&reqErr)
-		err = reqErr
-		return
-	}
-	header = resp.Response().Header
-	err = resp.ToJSON(&txs)
+	path := fmt.Sprintf("/transactions?per-page=%d&page=%d", TransactionsPerPage, page)
+	header, err = w.send(w.newCall("wallet.transactions", http.MethodGet, path), &txs)
 	return
 }
 
@@ -184,21 +403,15 @@ type InvoiceParams struct {
 // Invoice creates an invoice associated with this wallet.
 // https://docs.lnpay.co/wallet/generate-invoice
 func (w *Wallet) Invoice(params InvoiceParams) (lntx LnTx, err error) {
-	transactionLock.Lock()
-	resp, err := req.Post(w.BaseUrl+"/invoice", w.header, req.BodyJSON(&params))
-	transactionLock.Unlock()
-	if err != nil {
-		return
-	}
-
-	if resp.Response().StatusCode >= 300 {
-		var reqErr Error
-		resp.ToJSON(&reqErr)
-		err = reqErr
+	if err = params.Validate(); err != nil {
 		return
 	}
-
-	err = resp.ToJSON(&lntx)
+	call := w.newCall("wallet.invoice", http.MethodPost, "/invoice")
+	call.Attributes[AttrAmount] = params.NumSatoshis
+	unlock, wait := w.lockTransaction("invoice")
+	defer unlock()
+	call.Attributes[AttrLockWait] = wait.Milliseconds()
+	_, err = w.send(call, &lntx, req.BodyJSON(&params))
 	return
 }
 
@@ -213,21 +426,14 @@ type PayParams struct {
 // Pay pays a given invoice with funds from the wallet.
 // https://docs.lnpay.co/wallet/pay-invoice
 func (w *Wallet) Pay(params PayParams) (wtx Wtx, err error) {
-	transactionLock.Lock()
-	resp, err := req.Post(w.BaseUrl+"/withdraw", w.header, req.BodyJSON(&params))
-	transactionLock.Unlock()
-	if err != nil {
-		return
-	}
-
-	if resp.Response().StatusCode >= 300 {
-		var reqErr Error
-		resp.ToJSON(&reqErr)
-		err = reqErr
+	if err = params.Validate(); err != nil {
 		return
 	}
-
-	err = resp.ToJSON(&wtx)
+	call := w.newCall("wallet.withdraw", http.MethodPost, "/withdraw")
+	unlock, wait := w.lockTransaction("withdraw")
+	defer unlock()
+	call.Attributes[AttrLockWait] = wait.Milliseconds()
+	_, err = w.send(call, &wtx, req.BodyJSON(&params))
 	return
 }
 
@@ -240,20 +446,14 @@ type TransferParams struct {
 // Transfer transfers between two lnpay.co wallets.
 // https://docs.lnpay.co/wallet/transfers-between-wallets
 func (w *Wallet) Transfer(params TransferParams) (wtx Wtx, err error) {
-	transactionLock.Lock()
-	resp, err := req.Post(w.BaseUrl+"/transfer", w.header, req.BodyJSON(&params))
-	transactionLock.Unlock()
-	if err != nil {
+	if err = params.Validate(); err != nil {
 		return
 	}
-
-	if resp.Response().StatusCode >= 300 {
-		var reqErr Error
-		resp.ToJSON(&reqErr)
-		err = reqErr
-		return
-	}
-
-	err = resp.ToJSON(&wtx)
+	call := w.newCall("wallet.transfer", http.MethodPost, "/transfer")
+	call.Attributes[AttrAmount] = params.NumSatoshis
+	unlock, wait := w.lockTransaction("transfer")
+	defer unlock()
+	call.Attributes[AttrLockWait] = wait.Milliseconds()
+	_, err = w.send(call, &wtx, req.BodyJSON(&params))
 	return
 }
@@ -0,0 +1,72 @@
+package lnpay
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCreateWalletWithoutAdminKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer string
+		valid  bool
+	}{
+		{"admin key", `{"id":"wal_1","access_keys":{"Wallet Admin":["waka_1"]}}`, true},
+		{"no admin key", `{"id":"wal_1","access_keys":{"Wallet Invoice":["waki_1"]}}`, false},
+		{"no access keys", `{"id":"wal_1"}`, false},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(tt.answer))
+		}))
+		client := NewClient("sak_test")
+		client.SetBaseURL(server.URL)
+		wal, err := client.CreateWallet("shop")
+		server.Close()
+		if tt.valid && (err != nil || wal.BaseUrl != server.URL+"/wallet/waka_1") {
+			t.Errorf("%s: got %q %v, want the wallet of the admin key", tt.name, wal.BaseUrl, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidAnswer) {
+			t.Errorf("%s: got %v, want ErrInvalidAnswer", tt.name, err)
+		}
+	}
+}
+
+func TestLockTransactionIsPerWallet(t *testing.T) {
+	client := NewClient("sak_test")
+	shop, other := client.Wallet("waka_shop"), client.Wallet("waka_other")
+
+	unlock, _ := shop.lockTransaction("withdraw")
+	released := make(chan struct{})
+	go func() {
+		unlockOther, _ := other.lockTransaction("withdraw")
+		unlockOther()
+		close(released)
+	}()
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("an operation of another wallet waited for the lock")
+	}
+
+	locked := make(chan time.Duration)
+	go func() {
+		unlockShop, wait := client.Wallet("waka_shop").lockTransaction("transfer")
+		unlockShop()
+		locked <- wait
+	}()
+	time.Sleep(20 * time.Millisecond)
+	unlock()
+	if wait := <-locked; wait < 20*time.Millisecond {
+		t.Errorf("waited %s, want an operation of the same wallet to wait for the lock", wait)
+	}
+
+	transactionLocks.Lock()
+	defer transactionLocks.Unlock()
+	if len(transactionLocks.wallets) != 0 {
+		t.Errorf("%d wallet locks left, want them dropped once released", len(transactionLocks.wallets))
+	}
+}
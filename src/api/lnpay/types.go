@@ -228,3 +228,14 @@ type WtxType struct {
 	Name        string `json:"name"`
 	DisplayName string `json:"display_name"`
 }
+
+func (lntx *LnTx) amount() int64 {
+	return lntx.NumSatoshis
+}
+
+func (wtx *Wtx) amount() int64 {
+	if wtx.NumSatoshis < 0 {
+		return -wtx.NumSatoshis
+	}
+	return wtx.NumSatoshis
+}
@@ -0,0 +1,109 @@
+package lnpay
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxMemoLength is the longest description a BOLT11 invoice can carry, in bytes.
+const MaxMemoLength = 639
+
+var (
+	paymentRequestRegexp = regexp.MustCompile(`^ln(bc|tb|bcrt|sb|tbs)[0-9]*[munp]?1[qpzry9x8gf2tvdw0s3jn54khce6mua7l]+$`)
+	walletKeyRegexp      = regexp.MustCompile(`^(waka|waki|wakr|wal)_[A-Za-z0-9]+$`)
+)
+
+// FieldError describes why a field of the params is invalid.
+type FieldError struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+// ValidationErrors is returned when the params of a call are invalid. No request is made to lnpay.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, 0, len(v))
+	for _, e := range v {
+		messages = append(messages, e.Field+": "+e.Message)
+	}
+	return "invalid params: " + strings.Join(messages, "; ")
+}
+
+func (v ValidationErrors) orNil() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+// IsPaymentRequest reports whether value looks like a BOLT11 payment request. The checksum isn't verified.
+func IsPaymentRequest(value string) bool {
+	return paymentRequestRegexp.MatchString(strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(value, "lightning:"), "LIGHTNING:")))
+}
+
+// IsWalletKey reports whether value is a wallet access key (admin, invoice or read) or a wallet id.
+func IsWalletKey(value string) bool {
+	return walletKeyRegexp.MatchString(value)
+}
+
+// IsDescriptionHash reports whether value is a base64 encoded sha256 hash.
+func IsDescriptionHash(value string) bool {
+	hash, err := base64.StdEncoding.DecodeString(value)
+	return err == nil && len(hash) == 32
+}
+
+// Validate checks the params before creating an invoice. Invoices without an amount aren't created:
+// the payer would choose what to pay, out of the amount limits of the api.
+func (p InvoiceParams) Validate() error {
+	var errs ValidationErrors
+	if p.NumSatoshis <= 0 {
+		errs = append(errs, FieldError{"num_satoshis", "gt", "must be greater than zero"})
+	}
+	if p.Expiry < 0 {
+		errs = append(errs, FieldError{"expiry", "min", "must not be negative"})
+	}
+	if len(p.Memo) > MaxMemoLength {
+		errs = append(errs, FieldError{"memo", "max", fmt.Sprintf("must be at most %d bytes long", MaxMemoLength)})
+	}
+	if p.DescriptionHash != "" {
+		if p.Memo != "" {
+			errs = append(errs, FieldError{"description_hash", "excluded_with", "can't be set together with memo"})
+		}
+		if !IsDescriptionHash(p.DescriptionHash) {
+			errs = append(errs, FieldError{"description_hash", "description_hash", "must be a base64 encoded sha256 hash"})
+		}
+	}
+	return errs.orNil()
+}
+
+// Validate checks the params before paying an invoice.
+func (p PayParams) Validate() error {
+	var errs ValidationErrors
+	if p.PaymentRequest == "" {
+		errs = append(errs, FieldError{"payment_request", "required", "is required"})
+	} else if !IsPaymentRequest(p.PaymentRequest) {
+		errs = append(errs, FieldError{"payment_request", "bolt11", "must be a BOLT11 payment request"})
+	}
+	return errs.orNil()
+}
+
+// Validate checks the params before transferring between wallets.
+func (p TransferParams) Validate() error {
+	var errs ValidationErrors
+	if p.NumSatoshis <= 0 {
+		errs = append(errs, FieldError{"num_satoshis", "gt", "must be greater than zero"})
+	}
+	if len(p.Memo) > MaxMemoLength {
+		errs = append(errs, FieldError{"memo", "max", fmt.Sprintf("must be at most %d bytes long", MaxMemoLength)})
+	}
+	if p.DestWalletId == "" {
+		errs = append(errs, FieldError{"dest_wallet_id", "required", "is required"})
+	} else if !IsWalletKey(p.DestWalletId) {
+		errs = append(errs, FieldError{"dest_wallet_id", "walletkey", "must be a wallet key or id"})
+	}
+	return errs.orNil()
+}
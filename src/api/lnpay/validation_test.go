@@ -0,0 +1,40 @@
+package lnpay
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInvoiceParamsValidate(t *testing.T) {
+	hash := "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+	tests := []struct {
+		name   string
+		params InvoiceParams
+		codes  []string
+	}{
+		{"valid", InvoiceParams{NumSatoshis: 1, Memo: "coffee"}, nil},
+		{"description hash", InvoiceParams{NumSatoshis: 1, DescriptionHash: hash}, nil},
+		{"no amount", InvoiceParams{}, []string{"gt"}},
+		{"negative", InvoiceParams{NumSatoshis: -1, Expiry: -1}, []string{"gt", "min"}},
+		{"memo too long", InvoiceParams{NumSatoshis: 1, Memo: strings.Repeat("m", MaxMemoLength+1)}, []string{"max"}},
+		{"memo and hash", InvoiceParams{NumSatoshis: 1, Memo: "coffee", DescriptionHash: hash}, []string{"excluded_with"}},
+		{"invalid hash", InvoiceParams{NumSatoshis: 1, DescriptionHash: "abc"}, []string{"description_hash"}},
+	}
+	for _, tt := range tests {
+		err := tt.params.Validate()
+		var errs ValidationErrors
+		if err != nil && !errors.As(err, &errs) {
+			t.Fatalf("%s: got %v, want ValidationErrors", tt.name, err)
+		}
+		if len(errs) != len(tt.codes) {
+			t.Errorf("%s: got %v, want codes %v", tt.name, err, tt.codes)
+			continue
+		}
+		for i, e := range errs {
+			if e.Code != tt.codes[i] {
+				t.Errorf("%s: got code %s, want %s", tt.name, e.Code, tt.codes[i])
+			}
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"os"
+
 	"github.com/lnpay-wrapper-api-go/src/api/app"
 	"github.com/lnpay-wrapper-api-go/src/api/config"
 	_ "github.com/lnpay-wrapper-api-go/src/api/docs"
@@ -18,7 +22,19 @@ import (
 // @license.name Apache 2.0
 // @license.url http://www.apache.org/licenses/LICENSE-2.0.html
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-Api-Key
+
 func main() {
-	config.Load("config", "parameters", "yml")
+	if err := config.Load("config", "parameters", "yml"); err != nil {
+		var invalid config.ValidationError
+		if errors.As(err, &invalid) {
+			fmt.Fprintln(os.Stderr, invalid.Details())
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
 	app.Start()
 }
@@ -0,0 +1,154 @@
+/**
+* @author mnunez
+ */
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "lnpay_wrapper"
+
+// Registry holds every collector of the api, exposed by Handler.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by route and status.",
+	}, []string{"method", "route", "status"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the HTTP requests, by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	lnpayRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lnpay_requests_total",
+		Help:      "Calls to lnpay, by endpoint and status. Calls without an answer have the error status.",
+	}, []string{"endpoint", "status"})
+	lnpayDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "lnpay_request_duration_seconds",
+		Help:      "Latency of the calls to lnpay, by endpoint.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"endpoint"})
+	lnpayErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lnpay_errors_total",
+		Help:      "Failed calls to lnpay, by endpoint and kind: network, client_error, server_error, invalid_answer or circuit_open.",
+	}, []string{"endpoint", "kind"})
+	lockWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "lnpay_transaction_lock_wait_seconds",
+		Help:      "Time the invoices, payments and transfers waited for the transaction lock of their wallet.",
+		Buckets:   []float64{.001, .005, .01, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"operation"})
+
+	sats = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sats_total",
+		Help:      "Satoshis invoiced, paid and transferred, by operation.",
+	}, []string{"operation"})
+	settlement = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "invoice_settlement_seconds",
+		Help:      "Time between the creation and the settlement of the followed invoices.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 900, 1800, 3600, 86400},
+	})
+
+	webhooks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhooks_total",
+		Help:      "lnpay webhooks, by stage (received or processed) and outcome.",
+	}, []string{"stage", "outcome"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		lnpayRequests, lnpayDuration, lnpayErrors, lockWait,
+		sats, settlement, webhooks,
+	)
+}
+
+// Handler serves the metrics in the prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records an HTTP request served by the api. route is the route template,
+// so the ids in the paths don't create a series each.
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// Sats records the satoshis moved by an operation: invoiced, paid or transferred. They aren't broken
+// down by wallet, the labels only take values of the api so the callers can't create series.
+func Sats(operation string, amount int64) {
+	if amount < 0 {
+		amount = -amount
+	}
+	sats.WithLabelValues(operation).Add(float64(amount))
+}
+
+// Settlement records how long an invoice took to be paid.
+func Settlement(latency time.Duration) {
+	settlement.Observe(latency.Seconds())
+}
+
+// Webhook records the outcome of a webhook at the given stage.
+func Webhook(stage, outcome string) {
+	webhooks.WithLabelValues(stage, outcome).Inc()
+}
+
+// LNPayInterceptor records the count, the latency and the errors of the calls to lnpay.
+func LNPayInterceptor(call *lnpay.Call, next func() error) error {
+	start := time.Now()
+	err := next()
+	if errors.Is(err, lnpay.ErrCircuitOpen) {
+		lnpayErrors.WithLabelValues(call.Endpoint, "circuit_open").Inc()
+		return err
+	}
+
+	status := "error"
+	if call.Status > 0 {
+		status = strconv.Itoa(call.Status)
+	}
+	lnpayRequests.WithLabelValues(call.Endpoint, status).Inc()
+	lnpayDuration.WithLabelValues(call.Endpoint).Observe(time.Since(start).Seconds())
+
+	switch {
+	case err == nil:
+	case call.Status >= http.StatusInternalServerError:
+		lnpayErrors.WithLabelValues(call.Endpoint, "server_error").Inc()
+	case call.Status >= http.StatusMultipleChoices:
+		lnpayErrors.WithLabelValues(call.Endpoint, "client_error").Inc()
+	case call.Status > 0:
+		lnpayErrors.WithLabelValues(call.Endpoint, "invalid_answer").Inc()
+	default:
+		lnpayErrors.WithLabelValues(call.Endpoint, "network").Inc()
+	}
+	return err
+}
+
+// LockWait records the time an lnpay operation waited for the transaction lock.
+func LockWait(ctx context.Context, operation string, wait time.Duration) {
+	lockWait.WithLabelValues(operation).Observe(wait.Seconds())
+}
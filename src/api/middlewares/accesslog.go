@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/auth"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+)
+
+// AccessLogConfig sets which requests are logged. The requests changing state, moving money included,
+// and the failed requests are always logged, the reads are sampled.
+type AccessLogConfig struct {
+	// ReadSampleRate is the fraction of the successful GET and HEAD requests logged, 1 logs them all
+	ReadSampleRate float64
+}
+
+// AccessLog logs every request through the logger once it has been served, with its route, status, latency,
+// size, caller identity and wallet key fingerprint. The request id and the trace are added by the logger.
+func AccessLog(conf AccessLogConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		if !logAccess(c.Request.Method, status, conf.ReadSampleRate) {
+			return
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+		tags := []string{
+			"method:" + c.Request.Method,
+			"route:" + route,
+			"status:" + strconv.Itoa(status),
+			fmt.Sprintf("latency_ms:%.3f", float64(time.Since(start).Microseconds())/1000),
+			"bytes:" + strconv.Itoa(size),
+			"client_ip:" + c.ClientIP(),
+		}
+		if identity, ok := GetIdentity(c); ok {
+			tags = append(tags, "key_id:"+identity.KeyID)
+		}
+		if key := c.Param("key"); key != "" {
+			tags = append(tags, "wallet_fingerprint:"+walletFingerprint(key))
+		}
+
+		log := logger.WithContext(c.Request.Context()).Component(logger.ComponentHTTP)
+		message := c.Request.Method + " " + route + " " + strconv.Itoa(status)
+		if status >= http.StatusInternalServerError {
+			log.Warn(message, tags...)
+			return
+		}
+		log.Info(message, tags...)
+	}
+}
+
+func logAccess(method string, status int, readSampleRate float64) bool {
+	if status >= http.StatusBadRequest || (method != http.MethodGet && method != http.MethodHead) {
+		return true
+	}
+	return readSampleRate >= 1 || rand.Float64() < readSampleRate
+}
+
+// walletFingerprint identifies a wallet key in the logs without revealing it.
+func walletFingerprint(key string) string {
+	return auth.HashKey(key)[:16]
+}
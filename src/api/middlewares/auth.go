@@ -0,0 +1,166 @@
+package middlewares
+
+/**
+* @author mnunez
+ */
+
+import (
+	"crypto/x509"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/auth"
+	"github.com/lnpay-wrapper-api-go/src/api/storage"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+)
+
+const (
+	APIKeyHeader = "X-Api-Key"
+	identityKey  = "identity"
+)
+
+// Authenticate rejects the requests without a valid api key and attaches the caller identity to the context.
+// Requests without an api key are authenticated by their client certificate, when the server verified one.
+func Authenticate(keys storage.APIKeyStore, certs auth.CertIdentities) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := requestAPIKey(c)
+		if key == "" {
+			cert := clientCertificate(c)
+			if cert == nil {
+				abort(c, apierrors.NewUnauthorizedApiError("Missing api key"))
+				return
+			}
+			identity, ok := certs.Lookup(cert)
+			if !ok {
+				abort(c, apierrors.NewUnauthorizedApiError("Client certificate "+cert.Subject.String()+" not allowed"))
+				return
+			}
+			SetIdentity(c, identity)
+			c.Next()
+			return
+		}
+
+		apiKey, err := keys.GetByHash(auth.HashKey(key))
+		if err != nil {
+			if err != storage.ErrAPIKeyNotFound {
+				logger.WithContext(c.Request.Context()).Component(logger.ComponentHTTP).Error("Error looking up api key", err)
+			}
+			abort(c, apierrors.NewUnauthorizedApiError("Invalid api key"))
+			return
+		}
+		if apiKey.Revoked() {
+			abort(c, apierrors.NewUnauthorizedApiError("Revoked api key"))
+			return
+		}
+
+		SetIdentity(c, auth.Identity{
+			KeyID:   apiKey.ID,
+			Name:    apiKey.Name,
+			Scopes:  apiKey.Scopes,
+			Wallets: apiKey.Wallets,
+		})
+		c.Next()
+	}
+}
+
+// RequireScope rejects the requests whose caller wasn't granted scope.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := GetIdentity(c)
+		if !ok {
+			abort(c, apierrors.NewUnauthorizedApiError("Missing api key"))
+			return
+		}
+		if !identity.HasScope(scope) {
+			abort(c, apierrors.NewForbiddenApiError("The api key is missing the "+scope+" scope"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireWalletAccess rejects the requests on a wallet outside the caller allow-list.
+// The wallet is taken from the key path param and matched either by key or by its id.
+func RequireWalletAccess(wallets storage.WalletStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := GetIdentity(c)
+		if !ok {
+			abort(c, apierrors.NewUnauthorizedApiError("Missing api key"))
+			return
+		}
+
+		key := c.Param("key")
+		walletId := ""
+		if len(identity.Wallets) > 0 {
+			wallet, found, err := wallets.GetByKey(key)
+			if err != nil {
+				logger.WithContext(c.Request.Context()).Component(logger.ComponentHTTP).Error("Error looking up wallet", err)
+			}
+			if found {
+				walletId = wallet.ID
+			}
+		}
+		if !identity.CanAccessWallet(key, walletId) {
+			abort(c, apierrors.NewForbiddenApiError("The api key can't operate on this wallet"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAllWallets rejects the api keys restricted to some wallets, for the endpoints whose answers
+// can't be matched to a wallet, like the lightning transactions lnpay returns without their wallet.
+func RequireAllWallets() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := GetIdentity(c)
+		if !ok {
+			abort(c, apierrors.NewUnauthorizedApiError("Missing api key"))
+			return
+		}
+		if len(identity.Wallets) > 0 {
+			abort(c, apierrors.NewForbiddenApiError("The api key is restricted to some wallets, read the transactions of a wallet instead"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// SetIdentity attaches the caller identity to the gin context and to the request context.
+func SetIdentity(c *gin.Context, identity auth.Identity) {
+	c.Set(identityKey, identity)
+	c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), identity))
+}
+
+// GetIdentity returns the caller identity attached by Authenticate.
+func GetIdentity(c *gin.Context) (auth.Identity, bool) {
+	value, ok := c.Get(identityKey)
+	if !ok {
+		return auth.Identity{}, false
+	}
+	identity, ok := value.(auth.Identity)
+	return identity, ok
+}
+
+func requestAPIKey(c *gin.Context) string {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		return strings.TrimSpace(key)
+	}
+	if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	}
+	return ""
+}
+
+// clientCertificate returns the client certificate verified during the TLS handshake, if any.
+func clientCertificate(c *gin.Context) *x509.Certificate {
+	state := c.Request.TLS
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
+
+func abort(c *gin.Context, apiErr apierrors.ApiError) {
+	Render(c, apiErr)
+}
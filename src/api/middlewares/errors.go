@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/requestid"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/redact"
+)
+
+// Render writes err as the response of the request and stops the handlers chain.
+// Every handler and middleware answers its errors through here so they all look the same.
+// The document format is negotiated with the Accept header, falling back to the default format.
+// The secrets in the message, the causes and the instance are masked. The request id, when the request has one,
+// is added to the document so callers can report it. The error behind an internal error is logged, not answered.
+func Render(c *gin.Context, err error) {
+	apiErr := redacted(apierrors.FromError(err))
+	c.Error(apiErr)
+	if apiErr.Status() >= http.StatusInternalServerError && apiErr.Unwrap() != nil {
+		logger.WithContext(c.Request.Context()).Component(logger.ComponentHTTP).Error(apiErr.Message(), apiErr.Unwrap(),
+			"method:"+c.Request.Method, "route:"+c.FullPath())
+	}
+	id, hasID := requestid.FromContext(c.Request.Context())
+	if apierrors.NegotiateFormat(c.GetHeader("Accept")) == apierrors.FormatProblem {
+		problem := apierrors.NewProblem(apiErr, redact.String(c.Request.URL.RequestURI()))
+		if hasID {
+			problem.Extensions["request_id"] = id
+		}
+		body, e := json.Marshal(problem)
+		if e == nil {
+			c.Abort()
+			c.Data(apiErr.Status(), apierrors.ProblemContentType, body)
+			return
+		}
+	}
+	if hasID {
+		c.AbortWithStatusJSON(apiErr.Status(), legacyDocument{
+			Message:   apiErr.Message(),
+			Error:     apiErr.Code(),
+			Status:    apiErr.Status(),
+			Cause:     apiErr.Cause(),
+			RequestID: id,
+		})
+		return
+	}
+	c.AbortWithStatusJSON(apiErr.Status(), apiErr)
+}
+
+// legacyDocument is the legacy document of an ApiError along with the id of the request that failed.
+type legacyDocument struct {
+	Message   string              `json:"message"`
+	Error     string              `json:"error"`
+	Status    int                 `json:"status"`
+	Cause     apierrors.CauseList `json:"cause"`
+	RequestID string              `json:"request_id,omitempty"`
+}
+
+// redacted returns a copy of apiErr with the secrets masked in its message and causes.
+func redacted(apiErr apierrors.ApiError) apierrors.ApiError {
+	var cause apierrors.CauseList
+	if apiErr.Cause() != nil {
+		cause = make(apierrors.CauseList, 0, len(apiErr.Cause()))
+	}
+	for _, c := range apiErr.Cause() {
+		switch c := c.(type) {
+		case string:
+			cause = append(cause, redact.String(c))
+		case apierrors.FieldCause:
+			c.Message = redact.String(c.Message)
+			cause = append(cause, c)
+		case error:
+			cause = append(cause, redact.String(c.Error()))
+		default:
+			cause = append(cause, c)
+		}
+	}
+	redactedErr := apierrors.NewApiError(redact.String(apiErr.Message()), apiErr.Code(), apiErr.Status(), cause)
+	return apierrors.Wrap(redactedErr, apiErr.Unwrap())
+}
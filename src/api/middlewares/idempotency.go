@@ -0,0 +1,201 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/idempotency"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	idempotencyPollInterval  = 100 * time.Millisecond
+	// idempotencyStoreTimeout bounds the writes made once the request is served, they don't use the
+	// request context since it's canceled when the client goes away.
+	idempotencyStoreTimeout = 5 * time.Second
+)
+
+type IdempotencyConfig struct {
+	// TTL is how long the responses are kept for replays.
+	TTL time.Duration
+	// LockTTL bounds how long a request can hold a key without completing, e.g. when the process dies.
+	LockTTL time.Duration
+	// Wait is how long a duplicate waits for the first request before getting a conflict.
+	Wait time.Duration
+}
+
+// Idempotency honors the Idempotency-Key header: the first final response for a key is stored and replayed
+// for every retry with the same request, while a different request reusing the key is rejected. Only the
+// failures known to happen before reaching lnpay release the key so the request can be retried: a payment
+// that failed after reaching lnpay may still be completed by it, so its failure is replayed too.
+// Keys are scoped by api key, so it must run after Authenticate.
+func Idempotency(store idempotency.Store, conf IdempotencyConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(IdempotencyKeyHeader)
+		if header == "" {
+			c.Next()
+			return
+		}
+		if len(header) > maxIdempotencyKeyLength {
+			abort(c, apierrors.NewBadRequestApiError("Idempotency-Key can't be longer than 255 characters"))
+			return
+		}
+
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			abort(c, apierrors.NewBadRequestApiError("Error reading request body"))
+			return
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		identity, _ := GetIdentity(c)
+		key := "idem:" + identity.KeyID + ":" + header
+		fingerprint := requestFingerprint(c, body)
+		ctx := c.Request.Context()
+
+		record, acquired, err := store.Begin(ctx, key, fingerprint, conf.LockTTL)
+		if err != nil {
+			logger.WithContext(ctx).Component(logger.ComponentHTTP).Error("Error reserving idempotency key", err)
+			abort(c, apierrors.NewInternalServerApiError("Error reserving idempotency key", err))
+			return
+		}
+
+		if !acquired {
+			if record.Fingerprint != fingerprint {
+				abort(c, apierrors.NewApiError("Idempotency-Key was already used with a different request",
+					"idempotency_key_reused", http.StatusUnprocessableEntity, apierrors.CauseList{}))
+				return
+			}
+			record, err = waitCompletion(c, store, key, record, conf.Wait)
+			if err != nil || !record.Completed {
+				abort(c, apierrors.NewConflictApiError("idempotency key "+header))
+				return
+			}
+			replay(c, record)
+			return
+		}
+
+		// a panicking handler may have reached lnpay already, its retries get the internal error it answered
+		completed := false
+		defer func() {
+			if !completed {
+				save(ctx, store, key, panicRecord(fingerprint), conf.TTL)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		completed = true
+		if notExecuted(recorder.Status()) {
+			release(ctx, store, key)
+			return
+		}
+		save(ctx, store, key, idempotency.Record{
+			Fingerprint: fingerprint,
+			Completed:   true,
+			Status:      recorder.Status(),
+			Header:      recorder.Header().Clone(),
+			Body:        recorder.body.Bytes(),
+		}, conf.TTL)
+	}
+}
+
+// notExecuted reports whether a response is answered before anything was executed, so the request can be
+// retried with the same key: the invalid params, the rate limits and the open circuit or draining server.
+// Every other failure, e.g. an lnpay timeout, may come after lnpay made the payment and is replayed.
+func notExecuted(status int) bool {
+	return status == http.StatusBadRequest || status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// panicRecord is the internal error answered by Recovery to a panicking handler.
+func panicRecord(fingerprint string) idempotency.Record {
+	body, _ := json.Marshal(apierrors.NewInternalServerApiError("Internal server error", nil))
+	return idempotency.Record{
+		Fingerprint: fingerprint,
+		Completed:   true,
+		Status:      http.StatusInternalServerError,
+		Header:      http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+		Body:        body,
+	}
+}
+
+func save(ctx context.Context, store idempotency.Store, key string, record idempotency.Record, ttl time.Duration) {
+	storeCtx, cancel := context.WithTimeout(context.Background(), idempotencyStoreTimeout)
+	defer cancel()
+	if err := store.Complete(storeCtx, key, record, ttl); err != nil {
+		logger.WithContext(ctx).Component(logger.ComponentHTTP).Error("Error saving idempotent response", err)
+	}
+}
+
+func release(ctx context.Context, store idempotency.Store, key string) {
+	storeCtx, cancel := context.WithTimeout(context.Background(), idempotencyStoreTimeout)
+	defer cancel()
+	if err := store.Release(storeCtx, key); err != nil {
+		logger.WithContext(ctx).Component(logger.ComponentHTTP).Error("Error releasing idempotency key", err)
+	}
+}
+
+func waitCompletion(c *gin.Context, store idempotency.Store, key string, record idempotency.Record, wait time.Duration) (idempotency.Record, error) {
+	deadline := time.Now().Add(wait)
+	for !record.Completed && time.Now().Before(deadline) {
+		select {
+		case <-c.Request.Context().Done():
+			return record, c.Request.Context().Err()
+		case <-time.After(idempotencyPollInterval):
+		}
+		var err error
+		if record, err = store.Get(c.Request.Context(), key); err != nil {
+			return record, err
+		}
+	}
+	return record, nil
+}
+
+func replay(c *gin.Context, record idempotency.Record) {
+	header := c.Writer.Header()
+	for name, values := range record.Header {
+		if _, set := header[name]; !set {
+			header[name] = values
+		}
+	}
+	header.Set(IdempotentReplayedHeader, "true")
+	c.Writer.WriteHeader(record.Status)
+	c.Writer.Write(record.Body)
+	c.Abort()
+}
+
+func requestFingerprint(c *gin.Context, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder keeps a copy of everything written to the response.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,110 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/idempotency"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	logger.InitLog(logger.ConfigureLog{LoggingLevel: "panic", Format: logger.FormatText, Sinks: []string{"stderr"}})
+	os.Exit(m.Run())
+}
+
+// idempotentRouter serves POST /pay answering the statuses in order, panicking on a zero status.
+func idempotentRouter(statuses ...int) (*gin.Engine, *int) {
+	calls := 0
+	router := gin.New()
+	router.Use(Recovery())
+	router.POST("/pay", Idempotency(idempotency.NewMemoryStore(), IdempotencyConfig{
+		TTL: time.Hour, LockTTL: time.Minute, Wait: 0,
+	}), func(c *gin.Context) {
+		status := statuses[calls]
+		calls++
+		if status == 0 {
+			panic("handler failed")
+		}
+		c.JSON(status, gin.H{"call": calls})
+	})
+	return router, &calls
+}
+
+func post(router *gin.Engine, key string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/pay", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyOutcomes(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		// the status answered to the retry and whether it's a replay of the first response
+		retryStatus int
+		replayed    bool
+	}{
+		{"success is replayed", []int{201, 201}, 201, true},
+		{"unprocessable is replayed", []int{422, 201}, 422, true},
+		{"not found is replayed", []int{404, 201}, 404, true},
+		{"internal error is replayed", []int{500, 201}, 500, true},
+		{"bad gateway is replayed", []int{502, 201}, 502, true},
+		{"panic is replayed", []int{0, 201}, 500, true},
+		{"validation error is retried", []int{400, 201}, 201, false},
+		{"rate limited is retried", []int{429, 201}, 201, false},
+		{"unavailable is retried", []int{503, 201}, 201, false},
+	}
+	for _, tt := range tests {
+		router, calls := idempotentRouter(tt.statuses...)
+		post(router, "key-1", `{"amount":1}`)
+		retry := post(router, "key-1", `{"amount":1}`)
+
+		if retry.Code != tt.retryStatus {
+			t.Errorf("%s: retry got %d, want %d", tt.name, retry.Code, tt.retryStatus)
+		}
+		if replayed := retry.Header().Get(IdempotentReplayedHeader) == "true"; replayed != tt.replayed {
+			t.Errorf("%s: retry replayed %v, want %v", tt.name, replayed, tt.replayed)
+		}
+		wantCalls := 2
+		if tt.replayed {
+			wantCalls = 1
+		}
+		if *calls != wantCalls {
+			t.Errorf("%s: handler called %d times, want %d", tt.name, *calls, wantCalls)
+		}
+	}
+}
+
+func TestIdempotencyKeyReuse(t *testing.T) {
+	router, calls := idempotentRouter(201, 201, 201)
+
+	if w := post(router, "key-1", `{"amount":1}`); w.Code != http.StatusCreated {
+		t.Fatalf("got %d, want 201", w.Code)
+	}
+	if w := post(router, "key-1", `{"amount":2}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("other request with the key got %d, want 422", w.Code)
+	}
+	if w := post(router, "key-2", `{"amount":2}`); w.Code != http.StatusCreated {
+		t.Errorf("other key got %d, want 201", w.Code)
+	}
+	if w := post(router, "", `{"amount":2}`); w.Code != http.StatusCreated {
+		t.Errorf("no key got %d, want 201", w.Code)
+	}
+	if *calls != 3 {
+		t.Errorf("handler called %d times, want 3", *calls)
+	}
+	if w := post(router, strings.Repeat("k", maxIdempotencyKeyLength+1), `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("long key got %d, want 400", w.Code)
+	}
+}
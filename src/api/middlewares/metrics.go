@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/metrics"
+)
+
+// Metrics records the count and the latency of the requests by route template and status.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
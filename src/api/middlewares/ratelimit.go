@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/ratelimit"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+)
+
+// RateLimit rejects the requests exceeding the budget of the class, counted per client ip and,
+// once authenticated, per api key. The client ip is the remote address unless the request comes
+// from a trusted proxy. When the backend fails the request is let through.
+func RateLimit(limiter *ratelimit.Limiter, class ratelimit.Class) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, _ := GetIdentity(c)
+		result, err := limiter.Allow(c.Request.Context(), class, identity.KeyID, c.ClientIP())
+		if err != nil {
+			logger.WithContext(c.Request.Context()).Component(logger.ComponentHTTP).Error("Error checking rate limit", err, "class:"+string(class))
+			c.Next()
+			return
+		}
+
+		if result.Limit > 0 {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		}
+		if !result.Allowed {
+			retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			abort(c, apierrors.NewTooManyRequestsError("Rate limit exceeded, retry in "+strconv.Itoa(retryAfter)+" seconds"))
+			return
+		}
+		c.Next()
+	}
+}
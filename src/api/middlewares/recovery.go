@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"runtime/debug"
+	"strings"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+)
+
+// Recovery turns the panics of the handlers into an internal server error response,
+// logging the stack trace together with the request that caused it.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			err, ok := recovered.(error)
+			if !ok {
+				err = fmt.Errorf("%v", recovered)
+			}
+
+			tags := []string{
+				"method:" + c.Request.Method,
+				"path:" + c.Request.URL.Path,
+				"route:" + c.FullPath(),
+				"client_ip:" + c.ClientIP(),
+				"stack:" + string(debug.Stack()),
+			}
+			if identity, ok := GetIdentity(c); ok {
+				tags = append(tags, "key_id:"+identity.KeyID)
+			}
+			logger.WithContext(c.Request.Context()).Component(logger.ComponentHTTP).Error("Panic recovered", err, tags...)
+
+			if brokenPipe(err) {
+				// the client is gone, there is nobody to answer to
+				c.Error(err)
+				c.Abort()
+				return
+			}
+			Render(c, apierrors.NewInternalServerApiError("Internal server error", nil))
+		}()
+		c.Next()
+	}
+}
+
+func brokenPipe(err error) bool {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	var syscallErr *os.SyscallError
+	if errors.As(opErr, &syscallErr) {
+		return errors.Is(syscallErr.Err, syscall.EPIPE) || errors.Is(syscallErr.Err, syscall.ECONNRESET) ||
+			strings.Contains(strings.ToLower(syscallErr.Error()), "broken pipe")
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/requestid"
+)
+
+const requestIDKey = "request_id"
+
+// RequestID takes the request id from the X-Request-ID header, generating one when it's missing or invalid,
+// and stores it in the request context, where the logger and the lnpay client pick it up. The id is echoed
+// in the X-Request-ID response header.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.Generate()
+		}
+		c.Set(requestIDKey, id)
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+		c.Header(requestid.Header, id)
+		c.Next()
+	}
+}
+
+// GetRequestID returns the request id attached by RequestID.
+func GetRequestID(c *gin.Context) (string, bool) {
+	return requestid.FromContext(c.Request.Context())
+}
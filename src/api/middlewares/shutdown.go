@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/shutdown"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+)
+
+// Drain registers the request in the gate so a shutdown waits for it to end. Once the server
+// is shutting down new requests are rejected, the client should retry them on another instance.
+func Drain(gate *shutdown.Gate) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !gate.Enter() {
+			c.Header("Connection", "close")
+			c.Header("Retry-After", "1")
+			abort(c, apierrors.NewServiceUnavailableApiError("Server is shutting down, retry the request"))
+			return
+		}
+		defer gate.Leave()
+		c.Next()
+	}
+}
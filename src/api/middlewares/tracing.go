@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/tracing"
+)
+
+// Tracing starts a server span for every request, continuing the trace of the caller when it sends
+// a W3C traceparent header. The span is stored in the request context for the handlers and the lnpay calls.
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx := tracing.Extract(c.Request.Context(), c.Request.Header)
+		ctx, span := tracing.Start(ctx, c.Request.Method+" "+route, tracing.KindServer)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		tracing.Inject(ctx, c.Writer.Header())
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttribute("http.method", c.Request.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.status_code", status)
+		span.SetAttribute("http.client_ip", c.ClientIP())
+		if id, ok := GetRequestID(c); ok {
+			span.SetAttribute("http.request_id", id)
+		}
+		if identity, ok := GetIdentity(c); ok {
+			span.SetAttribute("auth.key_id", identity.KeyID)
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(tracing.StatusError, http.StatusText(status))
+		}
+		if err := c.Errors.Last(); err != nil {
+			span.SetAttribute("error.message", err.Error())
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/validation"
+)
+
+// ValidateWalletKey rejects the requests whose key path param isn't a wallet key before reaching lnpay.
+func ValidateWalletKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !validation.IsWalletKey(c.Param("key")) {
+			abort(c, apierrors.NewFieldValidationApiError("Invalid wallet key", apierrors.FieldCause{
+				Field:   "key",
+				Code:    "walletkey",
+				Message: "must be a wallet key or id",
+			}))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many calls the memory backend waits between sweeps of the expired windows.
+const sweepEvery = 1000
+
+type window struct {
+	count int
+	end   time.Time
+}
+
+type memoryBackend struct {
+	mu      sync.Mutex
+	windows map[string]*window
+	calls   int
+	now     func() time.Time
+}
+
+// NewMemoryBackend returns a Backend keeping the counters in the process memory.
+// Every instance of the api counts on its own.
+func NewMemoryBackend() Backend {
+	return &memoryBackend{
+		windows: make(map[string]*window),
+		now:     time.Now,
+	}
+}
+
+func (b *memoryBackend) Allow(_ context.Context, counters []Counter) ([]Result, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.calls++
+	if b.calls%sweepEvery == 0 {
+		for k, w := range b.windows {
+			if !now.Before(w.end) {
+				delete(b.windows, k)
+			}
+		}
+	}
+
+	windows := make([]*window, len(counters))
+	allowed := true
+	for i, counter := range counters {
+		w, ok := b.windows[counter.Key]
+		if !ok || !now.Before(w.end) {
+			w = &window{end: now.Add(counter.Limit.Period)}
+			b.windows[counter.Key] = w
+		}
+		windows[i] = w
+		allowed = allowed && w.count < counter.Limit.Requests
+	}
+
+	results := make([]Result, len(counters))
+	for i, counter := range counters {
+		w := windows[i]
+		if allowed {
+			w.count++
+		}
+		refused := !allowed && w.count >= counter.Limit.Requests
+		results[i] = newResult(w.count, counter.Limit, w.end.Sub(now), refused)
+	}
+	return results, nil
+}
+
+func (b *memoryBackend) Peek(_ context.Context, key string, limit Limit) (Result, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	w, ok := b.windows[key]
+	if !ok || !now.Before(w.end) {
+		return newResult(0, limit, 0, false), nil
+	}
+	return newResult(w.count, limit, w.end.Sub(now), w.count >= limit.Requests), nil
+}
+
+// newResult returns the state of a counter at count, refused when it has no room for the request.
+func newResult(count int, limit Limit, resetIn time.Duration, refused bool) Result {
+	if refused {
+		return Result{Allowed: false, Limit: limit.Requests, Remaining: 0, RetryAfter: resetIn}
+	}
+	remaining := limit.Requests - count
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Result{Allowed: true, Limit: limit.Requests, Remaining: remaining}
+}
@@ -0,0 +1,168 @@
+/**
+* @author mnunez
+ */
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Class groups the endpoints sharing the same budget.
+type Class string
+
+const (
+	// Read is the class of the endpoints that don't move money.
+	Read Class = "read"
+	// Money is the class of the endpoints that create invoices, pay or transfer.
+	Money Class = "money"
+)
+
+// Limit allows Requests every Period. A zero Requests disables the limit.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Disabled reports whether the limit lets every request through.
+func (l Limit) Disabled() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// Result is the outcome of checking a request against a limit.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Counter is a count of requests limited by Limit, stored under Key.
+type Counter struct {
+	Key   string
+	Limit Limit
+}
+
+// Backend counts the requests made by every key. Counters are kept in fixed windows of limit.Period.
+type Backend interface {
+	// Allow counts a request in every counter when all of them have room for it, none is charged
+	// otherwise. It returns the result of every counter, in order.
+	Allow(ctx context.Context, counters []Counter) ([]Result, error)
+	// Peek returns the state of the counter of key without counting a request.
+	Peek(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Budget is the pair of limits applied to a class of endpoints.
+type Budget struct {
+	PerKey Limit
+	PerIP  Limit
+}
+
+// Limiter applies the budget of every class on top of a backend.
+type Limiter struct {
+	backend Backend
+	mu      sync.RWMutex
+	budgets map[Class]Budget
+}
+
+func NewLimiter(backend Backend, budgets map[Class]Budget) *Limiter {
+	return &Limiter{backend: backend, budgets: budgets}
+}
+
+// SetBudgets replaces the budgets, the requests already counted in the current windows are kept.
+func (l *Limiter) SetBudgets(budgets map[Class]Budget) {
+	l.mu.Lock()
+	l.budgets = budgets
+	l.mu.Unlock()
+}
+
+// Budgets returns a copy of the budgets in use.
+func (l *Limiter) Budgets() map[Class]Budget {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	budgets := make(map[Class]Budget, len(l.budgets))
+	for class, budget := range l.budgets {
+		budgets[class] = budget
+	}
+	return budgets
+}
+
+type check struct {
+	scope string
+	key   string
+	limit Limit
+}
+
+func (l *Limiter) checks(class Class, keyId string, ip string) []check {
+	l.mu.RLock()
+	budget := l.budgets[class]
+	l.mu.RUnlock()
+	var checks []check
+	if ip != "" {
+		checks = append(checks, check{"ip", "rl:" + string(class) + ":ip:" + ip, budget.PerIP})
+	}
+	if keyId != "" {
+		checks = append(checks, check{"key", "rl:" + string(class) + ":key:" + keyId, budget.PerKey})
+	}
+	return checks
+}
+
+// Allow checks a request of the given class made from ip by the api key keyId, which may be empty.
+// The request is counted against the ip and the key only when both allow it, so a request refused
+// by one limit doesn't use the budget of the other. The most restrictive result is returned.
+func (l *Limiter) Allow(ctx context.Context, class Class, keyId string, ip string) (Result, error) {
+	var counters []Counter
+	for _, check := range l.checks(class, keyId, ip) {
+		if !check.limit.Disabled() {
+			counters = append(counters, Counter{Key: check.key, Limit: check.limit})
+		}
+	}
+	result := Result{Allowed: true, Remaining: -1}
+	if len(counters) == 0 {
+		return result, nil
+	}
+	results, err := l.backend.Allow(ctx, counters)
+	if err != nil {
+		return result, err
+	}
+	for _, r := range results {
+		switch {
+		case !r.Allowed:
+			if result.Allowed || r.RetryAfter > result.RetryAfter {
+				result = r
+			}
+		case result.Allowed && (result.Remaining < 0 || r.Remaining < result.Remaining):
+			result = r
+		}
+	}
+	return result, nil
+}
+
+// Usage is the state of the counter of an ip or api key in a class.
+type Usage struct {
+	Class  Class
+	Scope  string
+	Limit  Limit
+	Result Result
+}
+
+// Peek returns the state of the counters of the api key keyId and of ip in every class, without counting
+// a request. Either may be empty, disabled limits are skipped.
+func (l *Limiter) Peek(ctx context.Context, keyId string, ip string) ([]Usage, error) {
+	var usages []Usage
+	for _, class := range []Class{Read, Money} {
+		for _, check := range l.checks(class, keyId, ip) {
+			if check.limit.Disabled() {
+				continue
+			}
+			r, err := l.backend.Peek(ctx, check.key, check.limit)
+			if err != nil {
+				return nil, err
+			}
+			usages = append(usages, Usage{Class: class, Scope: check.scope, Limit: check.limit, Result: r})
+		}
+	}
+	return usages, nil
+}
@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// clock is a fake time source for the memory backend.
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newTestLimiter(budget Budget) (*Limiter, *clock) {
+	c := &clock{now: time.Unix(1700000000, 0)}
+	backend := NewMemoryBackend().(*memoryBackend)
+	backend.now = c.Now
+	return NewLimiter(backend, map[Class]Budget{Money: budget}), c
+}
+
+func TestLimiterAllow(t *testing.T) {
+	limiter, c := newTestLimiter(Budget{
+		PerKey: Limit{Requests: 2, Period: time.Minute},
+		PerIP:  Limit{Requests: 3, Period: time.Minute},
+	})
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		keyId     string
+		ip        string
+		allowed   bool
+		remaining int
+	}{
+		{"first request", "k1", "1.1.1.1", true, 1},
+		{"key budget left", "k1", "1.1.1.1", true, 0},
+		{"key exhausted", "k1", "1.1.1.1", false, 0},
+		// the request refused by the key didn't use the ip budget
+		{"other key", "k2", "1.1.1.1", true, 0},
+		{"ip exhausted", "k3", "1.1.1.1", false, 0},
+		{"other ip", "k3", "2.2.2.2", true, 1},
+		{"no key", "", "2.2.2.2", true, 1},
+	}
+	for _, tt := range tests {
+		result, err := limiter.Allow(ctx, Money, tt.keyId, tt.ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Allowed != tt.allowed || result.Remaining != tt.remaining {
+			t.Errorf("%s: got allowed %v remaining %d, want %v %d", tt.name, result.Allowed, result.Remaining, tt.allowed, tt.remaining)
+		}
+		if !result.Allowed && result.RetryAfter != time.Minute {
+			t.Errorf("%s: retry after %v, want the end of the window", tt.name, result.RetryAfter)
+		}
+	}
+
+	c.now = c.now.Add(time.Minute)
+	if result, _ := limiter.Allow(ctx, Money, "k1", "1.1.1.1"); !result.Allowed {
+		t.Error("refused after the window ended")
+	}
+}
+
+func TestLimiterDisabledLimits(t *testing.T) {
+	limiter, _ := newTestLimiter(Budget{PerKey: Limit{Requests: 1, Period: time.Minute}})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Allow(ctx, Read, "k1", "1.1.1.1")
+		if err != nil || !result.Allowed || result.Remaining != -1 {
+			t.Fatalf("class without budget: got %+v %v, want allowed without a count", result, err)
+		}
+	}
+	limiter.Allow(ctx, Money, "k1", "1.1.1.1")
+	if result, _ := limiter.Allow(ctx, Money, "k2", "1.1.1.1"); !result.Allowed {
+		t.Error("refused by the disabled ip limit")
+	}
+	if result, _ := limiter.Allow(ctx, Money, "k1", "2.2.2.2"); result.Allowed {
+		t.Error("allowed past the key limit")
+	}
+}
+
+func TestLimiterPeek(t *testing.T) {
+	limiter, _ := newTestLimiter(Budget{
+		PerKey: Limit{Requests: 1, Period: time.Minute},
+		PerIP:  Limit{Requests: 5, Period: time.Minute},
+	})
+	ctx := context.Background()
+	limiter.Allow(ctx, Money, "k1", "1.1.1.1")
+
+	for i := 0; i < 2; i++ {
+		// peeking doesn't count a request
+		usages, err := limiter.Peek(ctx, "k1", "1.1.1.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(usages) != 2 {
+			t.Fatalf("got %d usages, want the ip and key of the money class", len(usages))
+		}
+		ip, key := usages[0], usages[1]
+		if ip.Scope != "ip" || !ip.Result.Allowed || ip.Result.Remaining != 4 {
+			t.Errorf("ip usage %+v, want 4 remaining", ip)
+		}
+		if key.Scope != "key" || key.Result.Allowed || key.Result.RetryAfter != time.Minute {
+			t.Errorf("key usage %+v, want exhausted", key)
+		}
+	}
+}
+
+func TestSetBudgetsKeepsTheCounts(t *testing.T) {
+	limiter, _ := newTestLimiter(Budget{PerKey: Limit{Requests: 2, Period: time.Minute}})
+	ctx := context.Background()
+	limiter.Allow(ctx, Money, "k1", "")
+
+	limiter.SetBudgets(map[Class]Budget{Money: {PerKey: Limit{Requests: 1, Period: time.Minute}}})
+	if result, _ := limiter.Allow(ctx, Money, "k1", ""); result.Allowed {
+		t.Error("allowed past the new budget")
+	}
+	budgets := limiter.Budgets()
+	budgets[Money] = Budget{}
+	if limiter.Budgets()[Money].PerKey.Requests != 1 {
+		t.Error("Budgets returned the budgets in use instead of a copy")
+	}
+}
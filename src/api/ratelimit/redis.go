@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// allowScript increments the counter of KEYS[1] when it has room for the request, starting the window on its
+// first request. ARGV holds the requests and the period in milliseconds of the window. It returns whether the
+// request was allowed and the count and ttl of the key.
+var allowScript = redis.NewScript(`
+local count = tonumber(redis.call("GET", KEYS[1]) or "0")
+if count >= tonumber(ARGV[1]) then
+	return {0, count, redis.call("PTTL", KEYS[1])}
+end
+count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return {1, count, redis.call("PTTL", KEYS[1])}
+`)
+
+// refundScript gives back a request counted in KEYS[1], unless its window is already over. It returns the count.
+var refundScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+return redis.call("DECR", KEYS[1])
+`)
+
+type redisBackend struct {
+	client redis.UniversalClient
+}
+
+// NewRedisBackend returns a Backend keeping the counters in redis, shared by every instance of the api.
+func NewRedisBackend(client redis.UniversalClient) Backend {
+	return &redisBackend{client: client}
+}
+
+// Allow runs allowScript on every counter in turn, each script touches a single key so the counters can live in
+// different slots of a redis cluster. Once a counter refuses the request the counters already charged are
+// refunded and the rest are only read.
+func (b *redisBackend) Allow(ctx context.Context, counters []Counter) ([]Result, error) {
+	results := make([]Result, len(counters))
+	for i, counter := range counters {
+		values, err := allowScript.Run(ctx, b.client, []string{counter.Key},
+			counter.Limit.Requests, counter.Limit.Period.Milliseconds()).Int64Slice()
+		if err == nil && len(values) != 3 {
+			err = fmt.Errorf("unexpected rate limit script answer %v", values)
+		}
+		if err != nil {
+			b.refund(ctx, counters[:i])
+			return nil, err
+		}
+
+		count := int(values[1])
+		resetIn := time.Duration(values[2]) * time.Millisecond
+		if resetIn < 0 {
+			resetIn = counter.Limit.Period
+		}
+		if values[0] == 1 {
+			results[i] = newResult(count, counter.Limit, resetIn, false)
+			continue
+		}
+
+		results[i] = newResult(count, counter.Limit, resetIn, true)
+		refunded, err := b.refund(ctx, counters[:i])
+		if err != nil {
+			return nil, err
+		}
+		copy(results, refunded)
+		for j := i + 1; j < len(counters); j++ {
+			if results[j], err = b.Peek(ctx, counters[j].Key, counters[j].Limit); err != nil {
+				return nil, err
+			}
+		}
+		return results, nil
+	}
+	return results, nil
+}
+
+// refund gives back the request counted in counters, returning their results.
+func (b *redisBackend) refund(ctx context.Context, counters []Counter) ([]Result, error) {
+	results := make([]Result, len(counters))
+	for i, counter := range counters {
+		count, err := refundScript.Run(ctx, b.client, []string{counter.Key}).Int()
+		if err != nil {
+			return nil, err
+		}
+		results[i] = newResult(count, counter.Limit, 0, false)
+	}
+	return results, nil
+}
+
+func (b *redisBackend) Peek(ctx context.Context, key string, limit Limit) (Result, error) {
+	pipe := b.client.Pipeline()
+	get := pipe.Get(ctx, key)
+	ttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return Result{}, err
+	}
+	count, err := get.Int()
+	if err == redis.Nil {
+		return newResult(0, limit, 0, false), nil
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	resetIn := ttl.Val()
+	if resetIn < 0 {
+		resetIn = 0
+	}
+	return newResult(count, limit, resetIn, count >= limit.Requests), nil
+}
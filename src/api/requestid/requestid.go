@@ -0,0 +1,92 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+)
+
+// Header is the header the request id is read from and echoed in.
+const Header = "X-Request-ID"
+
+// MaxLength is the longest request id accepted from a caller.
+const MaxLength = 128
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the request id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request id stored in ctx, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Generate returns a new random request id.
+func Generate() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprintf("%032x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id)
+}
+
+// Valid reports whether id can be used as a request id: up to MaxLength letters, digits and "-_.:".
+// Anything else is replaced so the ids are safe to put in headers and logs.
+func Valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// LNPayInterceptor sends the request id of the request being served to lnpay and logs the call with it.
+func LNPayInterceptor(call *lnpay.Call, next func() error) error {
+	if id, ok := FromContext(call.Context); ok {
+		call.Header[Header] = id
+	}
+
+	start := time.Now()
+	err := next()
+	tags := []string{
+		"lnpay_endpoint:" + call.Endpoint,
+		"method:" + call.Method,
+		fmt.Sprintf("status:%d", call.Status),
+		fmt.Sprintf("duration_ms:%d", time.Since(start).Milliseconds()),
+	}
+	log := logger.WithContext(call.Context).Component(logger.ComponentLNPay)
+	if err != nil {
+		log.Error("lnpay call "+call.Endpoint+" failed", err, tags...)
+		return err
+	}
+	log.Debug("lnpay call "+call.Endpoint, tags...)
+	return nil
+}
+
+func init() {
+	logger.AddContextTags(func(ctx context.Context) []string {
+		if id, ok := FromContext(ctx); ok {
+			return []string{"request_id:" + id}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,83 @@
+/**
+* @author mnunez
+ */
+
+package shutdown
+
+import (
+	"context"
+	"sync"
+)
+
+// Gate tracks the requests that can't be interrupted, like the payments and transfers,
+// so the server stops taking new ones on shutdown and waits for the running ones to end.
+type Gate struct {
+	mu       sync.Mutex
+	closed   bool
+	inFlight int
+	drained  chan struct{}
+}
+
+func NewGate() *Gate {
+	return &Gate{drained: make(chan struct{})}
+}
+
+// Enter registers a new request. It returns false once the gate is closed,
+// otherwise Leave must be called when the request ends.
+func (g *Gate) Enter() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return false
+	}
+	g.inFlight++
+	return true
+}
+
+// Leave marks the end of a request registered with Enter.
+func (g *Gate) Leave() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inFlight--
+	if g.closed && g.inFlight == 0 {
+		close(g.drained)
+	}
+}
+
+// Close stops accepting new requests.
+func (g *Gate) Close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return
+	}
+	g.closed = true
+	if g.inFlight == 0 {
+		close(g.drained)
+	}
+}
+
+// Closed reports whether the gate stopped accepting requests.
+func (g *Gate) Closed() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.closed
+}
+
+// InFlight returns how many requests are running.
+func (g *Gate) InFlight() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.inFlight
+}
+
+// Wait closes the gate and blocks until the running requests end or ctx is done.
+func (g *Gate) Wait(ctx context.Context) error {
+	g.Close()
+	select {
+	case <-g.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,108 @@
+package storage
+
+/**
+* @author mnunez
+ */
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKey is a credential allowed to call the api. Only the hash of the key is stored.
+type APIKey struct {
+	ID        string
+	Name      string
+	Hash      string
+	Scopes    []string
+	Wallets   []string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// Revoked reports whether the key can't be used anymore.
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// APIKeyStore keeps the api keys issued to the callers.
+type APIKeyStore interface {
+	Save(key APIKey) error
+	Get(id string) (APIKey, error)
+	GetByHash(hash string) (APIKey, error)
+	List() ([]APIKey, error)
+	Revoke(id string, at time.Time) error
+}
+
+type memoryAPIKeyStore struct {
+	mu     sync.RWMutex
+	keys   map[string]APIKey
+	hashes map[string]string
+}
+
+// NewMemoryAPIKeyStore returns an APIKeyStore that lives in the process memory.
+func NewMemoryAPIKeyStore() APIKeyStore {
+	return &memoryAPIKeyStore{
+		keys:   make(map[string]APIKey),
+		hashes: make(map[string]string),
+	}
+}
+
+func (s *memoryAPIKeyStore) Save(key APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key.ID] = key
+	s.hashes[key.Hash] = key.ID
+	return nil
+}
+
+func (s *memoryAPIKeyStore) Get(id string) (APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[id]
+	if !ok {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+func (s *memoryAPIKeyStore) GetByHash(hash string) (APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[s.hashes[hash]]
+	if !ok {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+func (s *memoryAPIKeyStore) List() ([]APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+func (s *memoryAPIKeyStore) Revoke(id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[id]
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		key.RevokedAt = &at
+		s.keys[id] = key
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package storage
+
+/**
+* @author mnunez
+ */
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+)
+
+// WalletRecord is the information we keep about every wallet created through this api.
+type WalletRecord struct {
+	ID         string
+	UserLabel  string
+	CreatedAt  int
+	AccessKeys lnpay.AccessKeys
+}
+
+// HasKey reports whether key is one of the access keys of the wallet.
+func (w WalletRecord) HasKey(key string) bool {
+	for _, keys := range [][]string{w.AccessKeys.WalletAdmin, w.AccessKeys.WalletInvoice, w.AccessKeys.WalletRead} {
+		for _, k := range keys {
+			if k == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WalletStore keeps track of the wallets known by the api.
+type WalletStore interface {
+	Save(wallet WalletRecord) error
+	Get(id string) (WalletRecord, bool, error)
+	GetByKey(key string) (WalletRecord, bool, error)
+	List() ([]WalletRecord, error)
+}
+
+type memoryWalletStore struct {
+	mu      sync.RWMutex
+	wallets map[string]WalletRecord
+}
+
+// NewMemoryWalletStore returns a WalletStore that lives in the process memory.
+func NewMemoryWalletStore() WalletStore {
+	return &memoryWalletStore{wallets: make(map[string]WalletRecord)}
+}
+
+func (s *memoryWalletStore) Save(wallet WalletRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wallets[wallet.ID] = wallet
+	return nil
+}
+
+func (s *memoryWalletStore) Get(id string) (WalletRecord, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	wallet, ok := s.wallets[id]
+	return wallet, ok, nil
+}
+
+func (s *memoryWalletStore) GetByKey(key string) (WalletRecord, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, wallet := range s.wallets {
+		if wallet.HasKey(key) {
+			return wallet, true, nil
+		}
+	}
+	return WalletRecord{}, false, nil
+}
+
+func (s *memoryWalletStore) List() ([]WalletRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	wallets := make([]WalletRecord, 0, len(s.wallets))
+	for _, wallet := range s.wallets {
+		wallets = append(wallets, wallet)
+	}
+	sort.Slice(wallets, func(i, j int) bool {
+		if wallets[i].CreatedAt == wallets[j].CreatedAt {
+			return wallets[i].ID < wallets[j].ID
+		}
+		return wallets[i].CreatedAt < wallets[j].CreatedAt
+	})
+	return wallets, nil
+}
@@ -0,0 +1,164 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Exporter sends the ended spans to a tracing backend.
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+// Processor receives the spans as they end.
+type Processor interface {
+	OnEnd(span SpanData)
+	Shutdown(ctx context.Context) error
+}
+
+type noopProcessor struct{}
+
+func (noopProcessor) OnEnd(SpanData)                 {}
+func (noopProcessor) Shutdown(context.Context) error { return nil }
+
+// SimpleProcessor exports every span as soon as it ends, meant for the tests.
+type SimpleProcessor struct {
+	exporter Exporter
+	onError  func(error)
+}
+
+func NewSimpleProcessor(exporter Exporter, onError func(error)) *SimpleProcessor {
+	return &SimpleProcessor{exporter: exporter, onError: onError}
+}
+
+func (p *SimpleProcessor) OnEnd(span SpanData) {
+	if err := p.exporter.Export(context.Background(), []SpanData{span}); err != nil && p.onError != nil {
+		p.onError(err)
+	}
+}
+
+func (p *SimpleProcessor) Shutdown(ctx context.Context) error {
+	return p.exporter.Shutdown(ctx)
+}
+
+// BatchProcessor buffers the spans and exports them in batches of up to size spans, at least
+// every interval. When the buffer is full the new spans are dropped rather than blocking the requests.
+type BatchProcessor struct {
+	exporter Exporter
+	onError  func(error)
+	size     int
+	interval time.Duration
+	queue    chan SpanData
+	done     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+func NewBatchProcessor(exporter Exporter, size int, interval time.Duration, onError func(error)) *BatchProcessor {
+	p := &BatchProcessor{
+		exporter: exporter,
+		onError:  onError,
+		size:     size,
+		interval: interval,
+		queue:    make(chan SpanData, size*4),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+func (p *BatchProcessor) OnEnd(span SpanData) {
+	select {
+	case <-p.done:
+	case p.queue <- span:
+	default:
+	}
+}
+
+// Shutdown exports the buffered spans and shuts the exporter down.
+func (p *BatchProcessor) Shutdown(ctx context.Context) error {
+	p.once.Do(func() { close(p.done) })
+	select {
+	case <-p.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return p.exporter.Shutdown(ctx)
+}
+
+func (p *BatchProcessor) run() {
+	defer close(p.stopped)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, p.size)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := p.exporter.Export(context.Background(), batch); err != nil && p.onError != nil {
+			p.onError(err)
+		}
+		batch = make([]SpanData, 0, p.size)
+	}
+
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= p.size {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-p.done:
+			for {
+				select {
+				case span := <-p.queue:
+					batch = append(batch, span)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// MemoryExporter keeps the exported spans in memory, meant for the tests.
+type MemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func NewMemoryExporter() *MemoryExporter {
+	return &MemoryExporter{}
+}
+
+func (e *MemoryExporter) Export(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *MemoryExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// Spans returns the spans exported so far.
+func (e *MemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Reset forgets the spans exported so far.
+func (e *MemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+)
+
+// propagateToLNPay is 1 when the calls to lnpay carry the traceparent header.
+var propagateToLNPay int32
+
+// PropagateToLNPay sets whether the trace is propagated to lnpay with the traceparent header. It's off
+// by default: lnpay is a third party and the trace and span ids are kept internal.
+func PropagateToLNPay(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&propagateToLNPay, value)
+}
+
+// LNPayInterceptor traces every call to lnpay as a client span, child of the span of the request
+// being served, and propagates the trace to lnpay when enabled by PropagateToLNPay.
+func LNPayInterceptor(call *lnpay.Call, next func() error) error {
+	ctx, span := Start(call.Context, "lnpay "+call.Endpoint, KindClient)
+	defer span.End()
+	if atomic.LoadInt32(&propagateToLNPay) == 1 {
+		call.Header[TraceparentHeader] = FormatTraceparent(SpanContextFromContext(ctx))
+	}
+
+	err := next()
+	span.SetAttribute("lnpay.endpoint", call.Endpoint)
+	span.SetAttribute("http.method", call.Method)
+	if call.Status > 0 {
+		span.SetAttribute("http.status_code", call.Status)
+	}
+	for k, v := range call.Attributes {
+		span.SetAttribute(k, v)
+	}
+	if err != nil {
+		span.SetError(err)
+	} else if call.Status >= http.StatusBadRequest {
+		span.SetStatus(StatusError, http.StatusText(call.Status))
+	}
+	return err
+}
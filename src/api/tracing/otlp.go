@@ -0,0 +1,164 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// OTLPExporter sends the spans to an OpenTelemetry collector with OTLP over HTTP, JSON encoded.
+type OTLPExporter struct {
+	endpoint    string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+}
+
+// NewOTLPExporter returns an exporter posting to endpoint, e.g. http://collector:4318/v1/traces.
+func NewOTLPExporter(endpoint string, headers map[string]string, serviceName string, timeout time.Duration) *OTLPExporter {
+	return &OTLPExporter{
+		endpoint:    endpoint,
+		headers:     headers,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: timeout},
+	}
+}
+
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("otlp collector answered %s", resp.Status)
+	}
+	return nil
+}
+
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func (e *OTLPExporter) request(spans []SpanData) otlpRequest {
+	converted := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceID:           span.TraceID.String(),
+			SpanID:            span.SpanID.String(),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.Attributes),
+			Status:            otlpStatus{Code: span.Status, Message: span.StatusMessage},
+		}
+		if span.ParentSpanID.IsValid() {
+			s.ParentSpanID = span.ParentSpanID.String()
+		}
+		converted = append(converted, s)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes(map[string]interface{}{"service.name": e.serviceName})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/lnpay-wrapper-api-go/src/api/tracing"}, Spans: converted}},
+	}}}
+}
+
+func otlpAttributes(attributes map[string]interface{}) []otlpKeyValue {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	values := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		var value map[string]interface{}
+		switch v := attributes[k].(type) {
+		case string:
+			value = map[string]interface{}{"stringValue": v}
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		values = append(values, otlpKeyValue{Key: k, Value: value})
+	}
+	return values
+}
@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// TraceparentHeader is the W3C trace context header.
+const TraceparentHeader = "traceparent"
+
+// Extract returns a copy of ctx carrying the span context of the traceparent header, if valid.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc, ok := ParseTraceparent(header.Get(TraceparentHeader))
+	if !ok {
+		return ctx
+	}
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Inject sets the traceparent header of the span in ctx.
+func Inject(ctx context.Context, header http.Header) {
+	sc := SpanContextFromContext(ctx)
+	if sc.IsValid() {
+		header.Set(TraceparentHeader, FormatTraceparent(sc))
+	}
+}
+
+// ParseTraceparent parses a version 00 traceparent: 00-<trace id>-<parent id>-<flags>.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, false
+	}
+	var sc SpanContext
+	var flags [1]byte
+	if !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) || !decodeHex(flags[:], parts[3]) {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, sc.IsValid()
+}
+
+// FormatTraceparent returns the traceparent of sc.
+func FormatTraceparent(sc SpanContext) string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+func decodeHex(dst []byte, value string) bool {
+	if len(value) != hex.EncodedLen(len(dst)) || strings.ToLower(value) != value {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(value))
+	return err == nil
+}
@@ -0,0 +1,272 @@
+/**
+* @author mnunez
+ */
+
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/lnpay-wrapper-api-go/src/api/utils/logger"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/redact"
+)
+
+type SpanKind int
+
+// Span kinds, numbered as in OTLP.
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+type StatusCode int
+
+// Span status codes, numbered as in OTLP.
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+type TraceID [16]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+type SpanID [8]byte
+
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// SpanContext identifies a span, possibly started by another service.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	Remote  bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Span is an operation being traced. Its methods are safe to call on a nil span.
+type Span struct {
+	tracer *Tracer
+
+	mu            sync.Mutex
+	context       SpanContext
+	parent        SpanID
+	name          string
+	kind          SpanKind
+	start         time.Time
+	end           time.Time
+	attributes    map[string]interface{}
+	status        StatusCode
+	statusMessage string
+	ended         bool
+}
+
+// SpanData is the immutable copy of an ended span handed to the exporters.
+type SpanData struct {
+	TraceID       TraceID
+	SpanID        SpanID
+	ParentSpanID  SpanID
+	Name          string
+	Kind          SpanKind
+	Start         time.Time
+	End           time.Time
+	Attributes    map[string]interface{}
+	Status        StatusCode
+	StatusMessage string
+}
+
+// Context returns the identifiers of the span.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.context
+}
+
+// SetName renames the span, e.g. once the route of a request is known.
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+// SetAttribute records a key value pair describing the span, masking the secrets of the strings.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	if str, ok := value.(string); ok {
+		value = redact.String(str)
+	}
+	s.mu.Lock()
+	s.attributes[key] = value
+	s.mu.Unlock()
+}
+
+// SetError marks the span as failed with err.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.status = StatusError
+	s.statusMessage = redact.String(err.Error())
+	s.mu.Unlock()
+}
+
+// SetStatus sets the status of the span.
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.status = code
+	s.statusMessage = redact.String(message)
+	s.mu.Unlock()
+}
+
+// End finishes the span and hands it to the exporter. Only the first call has effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	data := SpanData{
+		TraceID:       s.context.TraceID,
+		SpanID:        s.context.SpanID,
+		ParentSpanID:  s.parent,
+		Name:          s.name,
+		Kind:          s.kind,
+		Start:         s.start,
+		End:           s.end,
+		Attributes:    make(map[string]interface{}, len(s.attributes)),
+		Status:        s.status,
+		StatusMessage: s.statusMessage,
+	}
+	for k, v := range s.attributes {
+		data.Attributes[k] = v
+	}
+	s.mu.Unlock()
+
+	if s.context.Sampled {
+		s.tracer.processor.OnEnd(data)
+	}
+}
+
+// Tracer starts spans and hands them to its processor once ended.
+type Tracer struct {
+	processor Processor
+}
+
+func NewTracer(processor Processor) *Tracer {
+	if processor == nil {
+		processor = noopProcessor{}
+	}
+	return &Tracer{processor: processor}
+}
+
+// Start starts a span, child of the span in ctx or of the remote span extracted into ctx.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	span := &Span{
+		tracer:     t,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: make(map[string]interface{}),
+	}
+
+	parent := SpanContextFromContext(ctx)
+	if parent.IsValid() {
+		span.context.TraceID = parent.TraceID
+		span.context.Sampled = parent.Sampled
+		span.parent = parent.SpanID
+	} else {
+		rand.Read(span.context.TraceID[:])
+		span.context.Sampled = true
+	}
+	rand.Read(span.context.SpanID[:])
+	return ContextWithSpan(ctx, span), span
+}
+
+// Shutdown exports the spans still buffered.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	return t.processor.Shutdown(ctx)
+}
+
+var global = NewTracer(nil)
+
+// Configure sets the tracer used by Start.
+func Configure(tracer *Tracer) {
+	global = tracer
+}
+
+// Start starts a span with the configured tracer.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	return global.Start(ctx, name, kind)
+}
+
+// Shutdown exports the spans still buffered by the configured tracer.
+func Shutdown(ctx context.Context) error {
+	return global.Shutdown(ctx)
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// ContextWithRemoteSpanContext returns a copy of ctx carrying the span of the caller.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanFromContext returns the current span, nil when there isn't any.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SpanContextFromContext returns the context of the current span, or of the remote one when there isn't any.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.Context()
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+func init() {
+	logger.AddContextTags(func(ctx context.Context) []string {
+		sc := SpanContextFromContext(ctx)
+		if !sc.IsValid() {
+			return nil
+		}
+		return []string{"trace_id:" + sc.TraceID.String(), "span_id:" + sc.SpanID.String()}
+	})
+}
@@ -0,0 +1,135 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+)
+
+func newTestTracer() (*Tracer, *MemoryExporter) {
+	exporter := NewMemoryExporter()
+	return NewTracer(NewSimpleProcessor(exporter, nil)), exporter
+}
+
+func TestSpansAreExportedWhenEnded(t *testing.T) {
+	tracer, exporter := newTestTracer()
+
+	ctx, parent := tracer.Start(context.Background(), "parent", KindServer)
+	_, child := tracer.Start(ctx, "child", KindClient)
+	if len(exporter.Spans()) != 0 {
+		t.Fatalf("spans exported before ending: %v", exporter.Spans())
+	}
+	child.End()
+	child.End()
+	parent.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if spans[0].Name != "child" || spans[1].Name != "parent" {
+		t.Errorf("got spans %q and %q, want child and parent", spans[0].Name, spans[1].Name)
+	}
+	if spans[0].TraceID != spans[1].TraceID {
+		t.Errorf("child trace %s, want the parent trace %s", spans[0].TraceID, spans[1].TraceID)
+	}
+	if spans[0].ParentSpanID != spans[1].SpanID {
+		t.Errorf("child parent %s, want %s", spans[0].ParentSpanID, spans[1].SpanID)
+	}
+}
+
+func TestRemoteParentIsContinued(t *testing.T) {
+	tracer, exporter := newTestTracer()
+	header := http.Header{}
+	header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	_, span := tracer.Start(Extract(context.Background(), header), "server", KindServer)
+	span.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if got := spans[0].TraceID.String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace %s, want the remote trace", got)
+	}
+	if got := spans[0].ParentSpanID.String(); got != "00f067aa0ba902b7" {
+		t.Errorf("parent %s, want the remote span", got)
+	}
+}
+
+func TestUnsampledSpansAreNotExported(t *testing.T) {
+	tracer, exporter := newTestTracer()
+	header := http.Header{}
+	header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+	_, span := tracer.Start(Extract(context.Background(), header), "server", KindServer)
+	span.End()
+
+	if spans := exporter.Spans(); len(spans) != 0 {
+		t.Errorf("got %d spans, want none", len(spans))
+	}
+}
+
+func TestSpanSecretsAreRedacted(t *testing.T) {
+	tracer, exporter := newTestTracer()
+
+	_, span := tracer.Start(context.Background(), "call", KindClient)
+	span.SetAttribute("key", "pak_O0iUMxk8kK_qUzkT4YKFvp1ZsUtp")
+	span.SetError(errors.New("invalid key waki_qUzkT4YKFvp1ZsUtp"))
+	span.End()
+
+	data := exporter.Spans()[0]
+	if got := data.Attributes["key"]; got != "pak_***sUtp" {
+		t.Errorf("attribute %q, want it masked", got)
+	}
+	if data.Status != StatusError || data.StatusMessage != "invalid key waki_***sUtp" {
+		t.Errorf("status %d %q, want an error with the key masked", data.Status, data.StatusMessage)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6-00f067aa0ba902b7-01", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		sc, ok := ParseTraceparent(tt.value)
+		if ok != tt.valid {
+			t.Errorf("ParseTraceparent(%q) valid %v, want %v", tt.value, ok, tt.valid)
+			continue
+		}
+		if ok && FormatTraceparent(sc)[3:] != tt.value[3:55] {
+			t.Errorf("FormatTraceparent(%q) = %q", tt.value, FormatTraceparent(sc))
+		}
+	}
+}
+
+func TestLNPayInterceptorPropagation(t *testing.T) {
+	defer PropagateToLNPay(false)
+	for _, propagate := range []bool{false, true} {
+		PropagateToLNPay(propagate)
+		call := &lnpay.Call{Context: context.Background(), Endpoint: "wallet.get", Header: map[string]string{}}
+		err := LNPayInterceptor(call, func() error { return nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, sent := call.Header[TraceparentHeader]
+		if sent != propagate {
+			t.Errorf("propagation %v: traceparent sent %v", propagate, sent)
+		}
+	}
+}
@@ -6,18 +6,28 @@ package apierrors
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 )
 
 type CauseList []interface{}
 
+// FieldCause is a machine readable cause, usually pointing to the request field that failed validation.
+type FieldCause struct {
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 type ApiError interface {
 	Message() string
 	Code() string
 	Status() int
 	Cause() CauseList
 	Error() string
+	// Unwrap returns the error that caused the ApiError, if any.
+	Unwrap() error
 }
 
 type apiErr struct {
@@ -25,6 +35,7 @@ type apiErr struct {
 	ErrorCode    string    `json:"error"`
 	ErrorStatus  int       `json:"status"`
 	ErrorCause   CauseList `json:"cause"`
+	err          error
 }
 
 func (c CauseList) ToString() string {
@@ -36,6 +47,9 @@ func (e apiErr) Code() string {
 }
 
 func (e apiErr) Error() string {
+	if e.err != nil {
+		return fmt.Sprintf("Message: %s;Error Code: %s;Status: %d;Cause: %v;Error: %v", e.ErrorMessage, e.ErrorCode, e.ErrorStatus, e.ErrorCause, e.err)
+	}
 	return fmt.Sprintf("Message: %s;Error Code: %s;Status: %d;Cause: %v", e.ErrorMessage, e.ErrorCode, e.ErrorStatus, e.ErrorCause)
 }
 
@@ -51,61 +65,113 @@ func (e apiErr) Message() string {
 	return e.ErrorMessage
 }
 
+func (e apiErr) Unwrap() error {
+	return e.err
+}
+
+// FieldCauses returns the structured causes of the error.
+func (c CauseList) FieldCauses() []FieldCause {
+	causes := []FieldCause{}
+	for _, cause := range c {
+		if fieldCause, ok := cause.(FieldCause); ok {
+			causes = append(causes, fieldCause)
+		}
+	}
+	return causes
+}
+
 func NewApiError(message string, error string, status int, cause CauseList) ApiError {
-	return apiErr{message, error, status, cause}
+	return apiErr{message, error, status, cause, nil}
+}
+
+// Wrap returns a copy of apiError that wraps err, so errors.Is and errors.As can reach it.
+func Wrap(apiError ApiError, err error) ApiError {
+	return apiErr{apiError.Message(), apiError.Code(), apiError.Status(), apiError.Cause(), err}
+}
+
+// FromError returns err as an ApiError, wrapping it in an internal server error when it isn't one.
+func FromError(err error) ApiError {
+	var apiErr ApiError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return NewInternalServerApiError("Internal server error", err)
 }
 
 func NewNotFoundApiError(message string) ApiError {
-	return apiErr{message, "not_found", http.StatusNotFound, CauseList{}}
+	return apiErr{message, "not_found", http.StatusNotFound, CauseList{}, nil}
 }
 
 func NewTooManyRequestsError(message string) ApiError {
-	return apiErr{message, "too_many_requests", http.StatusTooManyRequests, CauseList{}}
+	return apiErr{message, "too_many_requests", http.StatusTooManyRequests, CauseList{}, nil}
+}
+
+func NewServiceUnavailableApiError(message string) ApiError {
+	return apiErr{message, "service_unavailable", http.StatusServiceUnavailable, CauseList{}, nil}
 }
 
 func NewBadRequestApiError(message string) ApiError {
-	return apiErr{message, "bad_request", http.StatusBadRequest, CauseList{}}
+	return apiErr{message, "bad_request", http.StatusBadRequest, CauseList{}, nil}
 }
 
 func NewValidationApiError(message string, error string, cause CauseList) ApiError {
-	return apiErr{message, error, http.StatusBadRequest, cause}
+	return apiErr{message, error, http.StatusBadRequest, cause, nil}
+}
+
+// NewFieldValidationApiError returns a validation error with one structured cause per invalid field.
+func NewFieldValidationApiError(message string, causes ...FieldCause) ApiError {
+	cause := make(CauseList, 0, len(causes))
+	for _, c := range causes {
+		cause = append(cause, c)
+	}
+	return apiErr{message, "validation_error", http.StatusBadRequest, cause, nil}
 }
 
 func NewMethodNotAllowedApiError() ApiError {
-	return apiErr{"Method not allowed", "method_not_allowed", http.StatusMethodNotAllowed, CauseList{}}
+	return apiErr{"Method not allowed", "method_not_allowed", http.StatusMethodNotAllowed, CauseList{}, nil}
 }
 
+// NewInternalServerApiError returns an internal error wrapping err. err is kept for the logs and
+// errors.Is, it isn't part of the causes answered to the client.
 func NewInternalServerApiError(message string, err error) ApiError {
-	cause := CauseList{}
-	if err != nil {
-		cause = append(cause, err.Error())
-	}
-	return apiErr{message, "internal_server_error", http.StatusInternalServerError, cause}
+	return apiErr{message, "internal_server_error", http.StatusInternalServerError, CauseList{}, err}
+}
+
+// NewBadGatewayApiError returns the error answered when an upstream answer can't be used, wrapping err.
+func NewBadGatewayApiError(message string, err error) ApiError {
+	return apiErr{message, "bad_gateway", http.StatusBadGateway, CauseList{}, err}
 }
 
 func NewForbiddenApiError(message string) ApiError {
-	return apiErr{message, "forbidden", http.StatusForbidden, CauseList{}}
+	return apiErr{message, "forbidden", http.StatusForbidden, CauseList{}, nil}
 }
 
 func NewUnauthorizedApiError(message string) ApiError {
-	return apiErr{message, "unauthorized_scopes", http.StatusUnauthorized, CauseList{}}
+	return apiErr{message, "unauthorized_scopes", http.StatusUnauthorized, CauseList{}, nil}
 }
 
 func NewConflictApiError(id string) ApiError {
-	return apiErr{"Can't update " + id + " due to a conflict error", "conflict_error", http.StatusConflict, CauseList{}}
+	return apiErr{"Can't update " + id + " due to a conflict error", "conflict_error", http.StatusConflict, CauseList{}, nil}
 }
 
+// NewApiErrorFromBytes parses both the legacy error document and the problem details one.
 func NewApiErrorFromBytes(data []byte) (ApiError, error) {
+	if isProblem(data) {
+		var problem Problem
+		e := json.Unmarshal(data, &problem)
+		return problem.ApiError(), e
+	}
 	err := apiErr{}
 	e := json.Unmarshal(data, &err)
 	return err, e
 }
 
 func NewCustomStatusApiErrorFromBytes(data []byte, status int) (ApiError, error) {
-	var apierr apiErr
-	err := json.Unmarshal(data, &apierr)
-	if apierr.ErrorStatus == 0 {
-		apierr.ErrorStatus = status
+	apierr, err := NewApiErrorFromBytes(data)
+	if apierr.Status() == 0 {
+		e := apierr.(apiErr)
+		e.ErrorStatus = status
+		apierr = e
 	}
 	return apierr, err
 }
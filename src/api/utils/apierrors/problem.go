@@ -0,0 +1,147 @@
+package apierrors
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Format is the document an ApiError is rendered as.
+type Format string
+
+const (
+	// FormatLegacy is the {message,error,status,cause} document.
+	FormatLegacy Format = "legacy"
+	// FormatProblem is the RFC 7807 problem details document.
+	FormatProblem Format = "problem"
+)
+
+// ProblemTypeBase prefixes the error code to build the type URI of the problem documents.
+var ProblemTypeBase = "https://lnpay-wrapper-api-go/errors/"
+
+var defaultFormat = FormatLegacy
+
+// SetDefaultFormat sets the format used when the client doesn't ask for one in the Accept header.
+func SetDefaultFormat(format Format) {
+	if format == FormatProblem {
+		defaultFormat = FormatProblem
+		return
+	}
+	defaultFormat = FormatLegacy
+}
+
+// NegotiateFormat returns the format for a request with the given Accept header.
+func NegotiateFormat(accept string) Format {
+	if strings.Contains(accept, ProblemContentType) {
+		return FormatProblem
+	}
+	return defaultFormat
+}
+
+// Problem is an RFC 7807 problem details document.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+// NewProblem converts an ApiError into a problem details document about instance.
+// The error code and the causes are kept as extension members.
+func NewProblem(apiErr ApiError, instance string) Problem {
+	title := http.StatusText(apiErr.Status())
+	if title == "" {
+		title = apiErr.Code()
+	}
+	cause := apiErr.Cause()
+	if cause == nil {
+		cause = CauseList{}
+	}
+	return Problem{
+		Type:     ProblemTypeBase + apiErr.Code(),
+		Title:    title,
+		Status:   apiErr.Status(),
+		Detail:   apiErr.Message(),
+		Instance: instance,
+		Extensions: map[string]interface{}{
+			"error": apiErr.Code(),
+			"cause": cause,
+		},
+	}
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	document := make(map[string]interface{}, len(p.Extensions)+5)
+	for name, value := range p.Extensions {
+		document[name] = value
+	}
+	document["type"] = p.Type
+	document["title"] = p.Title
+	document["status"] = p.Status
+	if p.Detail != "" {
+		document["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		document["instance"] = p.Instance
+	}
+	return json.Marshal(document)
+}
+
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	*p = Problem{Extensions: map[string]interface{}{}}
+	members := map[string]interface{}{
+		"type":     &p.Type,
+		"title":    &p.Title,
+		"status":   &p.Status,
+		"detail":   &p.Detail,
+		"instance": &p.Instance,
+	}
+	for name, raw := range document {
+		if member, ok := members[name]; ok {
+			if err := json.Unmarshal(raw, member); err != nil {
+				return err
+			}
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+		p.Extensions[name] = value
+	}
+	return nil
+}
+
+// ApiError converts the problem back into an ApiError.
+func (p Problem) ApiError() ApiError {
+	code, _ := p.Extensions["error"].(string)
+	if code == "" {
+		code = strings.TrimPrefix(p.Type, ProblemTypeBase)
+	}
+	cause := CauseList{}
+	if list, ok := p.Extensions["cause"].([]interface{}); ok {
+		cause = CauseList(list)
+	}
+	message := p.Detail
+	if message == "" {
+		message = p.Title
+	}
+	return apiErr{message, code, p.Status, cause, nil}
+}
+
+// isProblem reports whether the json document has the shape of a problem details document.
+func isProblem(data []byte) bool {
+	var probe struct {
+		Type  *string `json:"type"`
+		Title *string `json:"title"`
+	}
+	return json.Unmarshal(data, &probe) == nil && (probe.Type != nil || probe.Title != nil)
+}
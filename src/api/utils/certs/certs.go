@@ -0,0 +1,122 @@
+/**
+* @author mnunez
+ */
+
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+// Client certificate verification modes.
+const (
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+// Options are the files and the client verification mode the server is configured with.
+type Options struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+	ClientAuth   string
+}
+
+// Enabled reports whether the server must be served over TLS.
+func (o Options) Enabled() bool {
+	return o.CertFile != "" || o.KeyFile != ""
+}
+
+type state struct {
+	certificate tls.Certificate
+	clientAuth  tls.ClientAuthType
+	clientCAs   *x509.CertPool
+}
+
+// Reloader serves the certificates loaded from the configured files, allowing to swap them
+// without restarting the server. New handshakes use the files loaded by the last Reload.
+type Reloader struct {
+	mu      sync.RWMutex
+	current state
+}
+
+// NewReloader loads the files of opts.
+func NewReloader(opts Options) (*Reloader, error) {
+	r := &Reloader{}
+	if err := r.Reload(opts); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the files of opts and starts using them. When they can't be loaded the
+// previous ones are kept.
+func (r *Reloader) Reload(opts Options) error {
+	loaded, err := load(opts)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.current = loaded
+	r.mu.Unlock()
+	return nil
+}
+
+// TLSConfig returns the server configuration resolving the certificates on every handshake.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   []string{"h2", "http/1.1"},
+				Certificates: []tls.Certificate{r.current.certificate},
+				ClientAuth:   r.current.clientAuth,
+				ClientCAs:    r.current.clientCAs,
+			}, nil
+		},
+	}
+}
+
+func load(opts Options) (state, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return state{}, errors.New("both the certificate and the key files are required")
+	}
+	certificate, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+	if err != nil {
+		return state{}, fmt.Errorf("loading certificate: %w", err)
+	}
+	loaded := state{certificate: certificate, clientAuth: tls.NoClientCert}
+
+	switch opts.ClientAuth {
+	case "", ClientAuthNone:
+		return loaded, nil
+	case ClientAuthOptional:
+		loaded.clientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		loaded.clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return state{}, fmt.Errorf("unknown client auth mode %q", opts.ClientAuth)
+	}
+
+	if opts.ClientCAFile == "" {
+		return state{}, errors.New("a client CA bundle is required to verify client certificates")
+	}
+	bundle, err := ioutil.ReadFile(opts.ClientCAFile)
+	if err != nil {
+		return state{}, fmt.Errorf("loading client CA bundle: %w", err)
+	}
+	loaded.clientCAs = x509.NewCertPool()
+	if !loaded.clientCAs.AppendCertsFromPEM(bundle) {
+		return state{}, fmt.Errorf("no certificates found in client CA bundle %s", opts.ClientCAFile)
+	}
+	return loaded, nil
+}
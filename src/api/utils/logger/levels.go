@@ -0,0 +1,163 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Components logging through Component, their levels can be overridden.
+const (
+	ComponentHTTP   = "http"
+	ComponentLNPay  = "lnpay"
+	ComponentEvents = "events"
+)
+
+// Levels is the level of the logger along with the levels overriding it for some components.
+type Levels struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components"`
+}
+
+// levels decides which entries are logged: an entry is logged when its level is at least as severe as the
+// level of its component, or the level of the logger for the entries without a component or an override.
+type levels struct {
+	mu         sync.RWMutex
+	level      logrus.Level
+	components map[string]logrus.Level
+}
+
+var currentLevels = &levels{level: logrus.InfoLevel, components: map[string]logrus.Level{}}
+
+func (l *levels) enabled(component string, level logrus.Level) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	threshold, ok := l.components[component]
+	if !ok {
+		threshold = l.level
+	}
+	return level <= threshold
+}
+
+// set replaces the levels.
+func (l *levels) set(level logrus.Level, components map[string]logrus.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.replace(level, components)
+}
+
+// update changes the levels with change, which gets the current level and a copy of the component
+// levels to modify. The levels are locked meanwhile so concurrent updates don't overwrite each other.
+func (l *levels) update(change func(level *logrus.Level, components map[string]logrus.Level)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	level, components := l.level, l.copyComponents()
+	change(&level, components)
+	l.replace(level, components)
+}
+
+// replace replaces the levels with l.mu held, the logrus logger lets through the entries of the most verbose one.
+func (l *levels) replace(level logrus.Level, components map[string]logrus.Level) {
+	l.level, l.components = level, components
+	verbose := level
+	for _, lvl := range components {
+		if lvl > verbose {
+			verbose = lvl
+		}
+	}
+	if Log != nil {
+		Log.SetLevel(verbose)
+	}
+}
+
+func (l *levels) get() (logrus.Level, map[string]logrus.Level) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.level, l.copyComponents()
+}
+
+func (l *levels) copyComponents() map[string]logrus.Level {
+	components := make(map[string]logrus.Level, len(l.components))
+	for name, lvl := range l.components {
+		components[name] = lvl
+	}
+	return components
+}
+
+// GetLevels returns the level of the logger and the component overrides.
+func GetLevels() Levels {
+	level, components := currentLevels.get()
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := Levels{Level: level.String(), Components: make(map[string]string, len(components))}
+	for _, name := range names {
+		result.Components[name] = components[name].String()
+	}
+	return result
+}
+
+// SetLevels replaces the level of the logger and the component overrides. Nothing changes when a level is invalid.
+func SetLevels(levels Levels) error {
+	level, err := logrus.ParseLevel(levels.Level)
+	if err != nil {
+		return err
+	}
+	components, err := parseComponentLevels(levels.Components)
+	if err != nil {
+		return err
+	}
+	currentLevels.set(level, components)
+	return nil
+}
+
+// SetLevel changes the level of the logger, keeping the component overrides.
+func SetLevel(level string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	currentLevels.update(func(level *logrus.Level, _ map[string]logrus.Level) {
+		*level = lvl
+	})
+	return nil
+}
+
+// SetComponentLevel overrides the level of a component, an empty level removes the override.
+func SetComponentLevel(component string, level string) error {
+	if level == "" {
+		currentLevels.update(func(_ *logrus.Level, components map[string]logrus.Level) {
+			delete(components, component)
+		})
+		return nil
+	}
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	currentLevels.update(func(_ *logrus.Level, components map[string]logrus.Level) {
+		components[component] = lvl
+	})
+	return nil
+}
+
+// IsEnabled reports whether the entries of component at level are logged, an empty component uses the level of the logger.
+func IsEnabled(component string, level logrus.Level) bool {
+	return currentLevels.enabled(component, level)
+}
+
+func parseComponentLevels(levels map[string]string) (map[string]logrus.Level, error) {
+	components := make(map[string]logrus.Level, len(levels))
+	for name, level := range levels {
+		lvl, err := logrus.ParseLevel(level)
+		if err != nil {
+			return nil, fmt.Errorf("component %s: %w", name, err)
+		}
+		components[name] = lvl
+	}
+	return components, nil
+}
@@ -0,0 +1,218 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+type recordedEntry struct {
+	Level     string `json:"level"`
+	Message   string `json:"message"`
+	Component string `json:"component"`
+}
+
+// recorder is a sink keeping the entries written to it.
+type recorder struct {
+	mu      sync.Mutex
+	entries []recordedEntry
+}
+
+func (r *recorder) Write(level logrus.Level, line []byte) error {
+	var entry recordedEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *recorder) Close() error {
+	return nil
+}
+
+func (r *recorder) take() []recordedEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := r.entries
+	r.entries = nil
+	return entries
+}
+
+// capture configures the logger with the levels and returns the sink receiving its entries.
+func capture(t *testing.T, level string, components map[string]string) *recorder {
+	t.Helper()
+	InitLog(ConfigureLog{LoggingLevel: level, Format: FormatJSON, Sinks: []string{"stderr"}, ComponentLevels: components})
+	r := &recorder{}
+	out.swap(nil, []Sink{r})
+	return r
+}
+
+var errTest = errors.New("boom")
+
+func TestLevelHelpers(t *testing.T) {
+	helpers := []struct {
+		name    string
+		level   logrus.Level
+		log     func()
+		message string
+	}{
+		{"Debug", logrus.DebugLevel, func() { Debug("debug") }, "debug"},
+		{"Debugf", logrus.DebugLevel, func() { Debugf("debug %d", 1) }, "debug 1"},
+		{"Info", logrus.InfoLevel, func() { Info("info") }, "info"},
+		{"Infof", logrus.InfoLevel, func() { Infof("info %d", 1) }, "info 1"},
+		{"Warn", logrus.WarnLevel, func() { Warn("warn") }, "warn"},
+		{"Warnf", logrus.WarnLevel, func() { Warnf("warn %d", 1) }, "warn 1"},
+		{"Error", logrus.ErrorLevel, func() { Error("error", errTest) }, "error - ERROR: boom"},
+		{"Errorf", logrus.ErrorLevel, func() { Errorf("error %d", errTest, 1) }, "error 1 - ERROR: boom"},
+	}
+	for _, configured := range []string{"trace", "debug", "info", "warn", "error", "fatal"} {
+		threshold, _ := logrus.ParseLevel(configured)
+		r := capture(t, configured, nil)
+		for _, helper := range helpers {
+			helper.log()
+			entries := r.take()
+			if helper.level > threshold {
+				if len(entries) != 0 {
+					t.Errorf("%s at level %s: logged %+v, want nothing", helper.name, configured, entries)
+				}
+				continue
+			}
+			if len(entries) != 1 || entries[0].Level != helper.level.String() || entries[0].Message != helper.message {
+				t.Errorf("%s at level %s: logged %+v, want %s %q", helper.name, configured, entries, helper.level, helper.message)
+			}
+		}
+	}
+}
+
+func TestPanicHelpers(t *testing.T) {
+	helpers := []struct {
+		name    string
+		log     func()
+		message string
+	}{
+		{"Panic", func() { Panic("panic", errTest) }, "panic - PANIC: boom"},
+		{"Panicf", func() { Panicf("panic %d", errTest, 1) }, "panic 1 - PANIC: boom"},
+	}
+	for _, helper := range helpers {
+		// the panics are logged whatever the level
+		r := capture(t, "panic", nil)
+		recovered := func() (recovered interface{}) {
+			defer func() { recovered = recover() }()
+			helper.log()
+			return nil
+		}()
+		if recovered == nil {
+			t.Errorf("%s didn't panic", helper.name)
+		}
+		entries := r.take()
+		if len(entries) != 1 || entries[0].Level != "panic" || entries[0].Message != helper.message {
+			t.Errorf("%s logged %+v, want %q", helper.name, entries, helper.message)
+		}
+	}
+}
+
+func TestComponentLevels(t *testing.T) {
+	r := capture(t, "info", map[string]string{ComponentLNPay: "debug", ComponentHTTP: "error"})
+	tests := []struct {
+		component string
+		level     logrus.Level
+		logged    bool
+	}{
+		{ComponentLNPay, logrus.DebugLevel, true},
+		{ComponentHTTP, logrus.WarnLevel, false},
+		{ComponentHTTP, logrus.ErrorLevel, true},
+		{ComponentEvents, logrus.InfoLevel, true},
+		{ComponentEvents, logrus.DebugLevel, false},
+		{"", logrus.DebugLevel, false},
+	}
+	for _, tt := range tests {
+		if got := IsEnabled(tt.component, tt.level); got != tt.logged {
+			t.Errorf("IsEnabled(%q, %s) = %v, want %v", tt.component, tt.level, got, tt.logged)
+		}
+		log := Component(tt.component)
+		switch tt.level {
+		case logrus.DebugLevel:
+			log.Debug("entry")
+		case logrus.InfoLevel:
+			log.Info("entry")
+		case logrus.WarnLevel:
+			log.Warn("entry")
+		default:
+			log.Error("entry", errTest)
+		}
+		entries := r.take()
+		if logged := len(entries) == 1; logged != tt.logged {
+			t.Errorf("%q at %s: logged %+v, want logged %v", tt.component, tt.level, entries, tt.logged)
+		}
+		if tt.logged && tt.component != "" && entries[0].Component != tt.component {
+			t.Errorf("%q at %s: component %q", tt.component, tt.level, entries[0].Component)
+		}
+	}
+	if Log.GetLevel() != logrus.DebugLevel {
+		t.Errorf("logrus level %s, want the most verbose of the levels", Log.GetLevel())
+	}
+}
+
+func TestSetLevels(t *testing.T) {
+	capture(t, "info", map[string]string{ComponentLNPay: "debug"})
+
+	if err := SetLevels(Levels{Level: "warn", Components: map[string]string{ComponentHTTP: "nope"}}); err == nil {
+		t.Error("SetLevels accepted an invalid component level")
+	}
+	if err := SetLevel("nope"); err == nil {
+		t.Error("SetLevel accepted an invalid level")
+	}
+	want := Levels{Level: "info", Components: map[string]string{ComponentLNPay: "debug"}}
+	if got := GetLevels(); got.Level != want.Level || len(got.Components) != 1 || got.Components[ComponentLNPay] != "debug" {
+		t.Fatalf("levels changed by invalid updates: %+v", got)
+	}
+
+	if err := SetLevel("error"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetComponentLevel(ComponentEvents, "trace"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetComponentLevel(ComponentLNPay, ""); err != nil {
+		t.Fatal(err)
+	}
+	got := GetLevels()
+	if got.Level != "error" || len(got.Components) != 1 || got.Components[ComponentEvents] != "trace" {
+		t.Errorf("got %+v, want error with events at trace", got)
+	}
+	if Log.GetLevel() != logrus.TraceLevel {
+		t.Errorf("logrus level %s, want trace", Log.GetLevel())
+	}
+}
+
+func TestConcurrentComponentLevels(t *testing.T) {
+	capture(t, "info", nil)
+
+	components := make([]string, 50)
+	var wg sync.WaitGroup
+	for i := range components {
+		components[i] = fmt.Sprintf("component-%d", i)
+		wg.Add(2)
+		go func(component string) {
+			defer wg.Done()
+			SetComponentLevel(component, "debug")
+		}(components[i])
+		go func() {
+			defer wg.Done()
+			SetLevel("warn")
+		}()
+	}
+	wg.Wait()
+
+	got := GetLevels()
+	if got.Level != "warning" || len(got.Components) != len(components) {
+		t.Errorf("got level %s and %d components, want warn and every component set", got.Level, len(got.Components))
+	}
+}
@@ -5,15 +5,14 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 	"runtime/debug"
 	"strings"
 
 	"github.com/sirupsen/logrus"
-	prefixed "github.com/x-cray/logrus-prefixed-formatter"
 )
 
 var Log *logrus.Logger
@@ -24,44 +23,50 @@ type ConfigureLog struct {
 	LoggingPath  string
 	LoggingFile  string
 	LoggingLevel string
+	// Format is either text or json
+	Format string
+	// Sinks are stderr, file and syslog, the file when empty
+	Sinks    []string
+	Rotation Rotation
+	Syslog   Syslog
+	// ComponentLevels override LoggingLevel for the entries of some components, like lnpay or http
+	ComponentLevels map[string]string
 }
 
+// out writes the entries of Log to the configured sinks.
+var out *output
+
 func InitLog(configureLog ConfigureLog) {
-	var loggingPath = configureLog.LoggingPath
-	var loggingFile = configureLog.LoggingFile
-	var loggingLevel = configureLog.LoggingLevel
 	Log = logrus.New()
-	// Creating log dir if not exists
-	if _, err := os.Stat(loggingPath); os.IsNotExist(err) {
-		if err = os.MkdirAll(loggingPath, 0777); err != nil {
-			if os.IsPermission(err) {
-				fmt.Println("Try fix the permission issue, by creating the dir structure and try again.")
-				panic(err)
-			}
-		}
+	// the entries are formatted and written by out, the logger itself writes nowhere
+	Log.SetOutput(io.Discard)
+	Log.Formatter = discardFormatter{}
+	out = &output{formatter: newFormatter(configureLog.Format), sinks: []Sink{&writerSink{w: os.Stderr}}}
+	Log.AddHook(out)
+
+	if err := Reconfigure(configureLog); err != nil {
+		fmt.Println("Failed to configure the log sinks, using default stderr : ", err)
 	}
+}
 
-	f := filepath.Join(loggingPath, loggingFile)
-	fmt.Printf("Logging on : %s\n", f)
-	file, err := os.OpenFile(f, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0777)
-	if err == nil {
-		Log.SetOutput(file)
-	} else {
-		fmt.Println("Failed to log to file, using default stderr : ", err)
-		Log.SetOutput(os.Stderr)
+// Reconfigure switches the levels, the format and the sinks of the logger at runtime.
+// Nothing changes when a component level is invalid, the previous sinks are kept when the new ones can't be opened.
+func Reconfigure(configureLog ConfigureLog) error {
+	components, err := parseComponentLevels(configureLog.ComponentLevels)
+	if err != nil {
+		return err
 	}
-	Log.SetOutput(file)
-	// Log as JSON instead of the default ASCII formatter.
-	Log.Formatter = new(prefixed.TextFormatter)
-	Log.Formatter.(*prefixed.TextFormatter).ForceFormatting = true
-	Log.Formatter.(*prefixed.TextFormatter).FullTimestamp = true
-
-	// Only log the warning severity or above.
-	lvl, err := logrus.ParseLevel(loggingLevel)
+	lvl, err := logrus.ParseLevel(configureLog.LoggingLevel)
 	if err != nil {
 		lvl = logrus.InfoLevel
 	}
-	Log.SetLevel(lvl)
+	currentLevels.set(lvl, components)
+
+	sinks, err := newSinks(configureLog)
+	if err != nil {
+		return err
+	}
+	return closeSinks(out.swap(newFormatter(configureLog.Format), sinks))
 }
 
 func Print(e interface{}) {
@@ -69,79 +74,146 @@ func Print(e interface{}) {
 }
 
 func Debug(message string, tags ...string) {
-	if Log.Level > logrus.DebugLevel {
-		tags = append(tags, "level:debug")
-		entry, message := buildLogEntryWithMessage(tags, message)
-		entry.Debug(message)
-	}
+	log("", logrus.DebugLevel, message, tags)
 }
 
 func Info(message string, tags ...string) {
-	if Log.Level > logrus.InfoLevel {
-		tags = append(tags, "level:info")
-		entry, message := buildLogEntryWithMessage(tags, message)
-		entry.Info(message)
-	}
+	log("", logrus.InfoLevel, message, tags)
 }
 
 func Warn(message string, tags ...string) {
-	if Log.Level > logrus.WarnLevel {
-		tags = append(tags, "level:warn")
-		entry, message := buildLogEntryWithMessage(tags, message)
-		entry.Info(message)
-	}
+	log("", logrus.WarnLevel, message, tags)
 }
 
 func Error(message string, err error, tags ...string) {
-	if Log.Level > logrus.ErrorLevel {
-		tags = append(tags, "level:error")
-		msg := fmt.Sprintf("%s - ERROR: %v", message, err)
-		entry, msg := buildLogEntryWithMessage(tags, msg)
-		entry.Error(msg)
-	}
+	log("", logrus.ErrorLevel, errorMessage(message, err), tags)
 }
 
+// Panic logs the message and panics, whatever the level.
 func Panic(message string, err error, tags ...string) {
-	if Log.Level > logrus.PanicLevel {
-		tags = append(tags, "level:panic")
-		msg := fmt.Sprintf("%s - PANIC: %v", message, err)
-		entry, msg := buildLogEntryWithMessage(tags, msg)
-		entry.Panic(msg)
-	}
+	log("", logrus.PanicLevel, panicMessage(message, err), tags)
 }
 
 func Debugf(format string, args ...interface{}) {
-	if Log.Level > logrus.DebugLevel {
+	if IsEnabled("", logrus.DebugLevel) {
 		Debug(fmt.Sprintf(format, args...))
 	}
 }
 
 func Infof(format string, args ...interface{}) {
-	if Log.Level > logrus.InfoLevel {
+	if IsEnabled("", logrus.InfoLevel) {
 		Info(fmt.Sprintf(format, args...))
 	}
 }
 
 func Warnf(format string, args ...interface{}) {
-	if Log.Level > logrus.WarnLevel {
+	if IsEnabled("", logrus.WarnLevel) {
 		Warn(fmt.Sprintf(format, args...))
 	}
 }
 
 func Errorf(format string, err error, args ...interface{}) {
-	if Log.Level > logrus.ErrorLevel {
+	if IsEnabled("", logrus.ErrorLevel) {
 		Error(fmt.Sprintf(format, args...), err)
 	}
 }
 
 func Panicf(format string, err error, args ...interface{}) {
-	if Log.Level > logrus.PanicLevel {
-		Panic(fmt.Sprintf(format, args...), err)
+	Panic(fmt.Sprintf(format, args...), err)
+}
+
+// log writes the entry when the level is enabled for the component, logrus panics on the panic level.
+func log(component string, level logrus.Level, message string, tags []string) {
+	if !IsEnabled(component, level) {
+		return
+	}
+	if component != "" {
+		tags = append(tags, "component:"+component)
+	}
+	entry, message := buildLogEntryWithMessage(tags, message)
+	entry.Log(level, message)
+}
+
+func errorMessage(message string, err error) string {
+	return fmt.Sprintf("%s - ERROR: %v", message, err)
+}
+
+func panicMessage(message string, err error) string {
+	return fmt.Sprintf("%s - PANIC: %v", message, err)
+}
+
+var contextTags []func(ctx context.Context) []string
+
+// AddContextTags registers fn to add the tags it takes from a context, like the trace id,
+// to the entries logged WithContext.
+func AddContextTags(fn func(ctx context.Context) []string) {
+	contextTags = append(contextTags, fn)
+}
+
+// ContextLogger logs adding the tags carried by the context of a request, and the component when it has one.
+type ContextLogger struct {
+	component string
+	tags      []string
+}
+
+// WithContext returns a logger adding the tags registered with AddContextTags to every entry.
+func WithContext(ctx context.Context) ContextLogger {
+	var tags []string
+	if ctx != nil {
+		for _, fn := range contextTags {
+			tags = append(tags, fn(ctx)...)
+		}
 	}
+	return ContextLogger{tags: tags}
+}
+
+// Component returns a logger for the entries of component, logged according to the level of the component.
+func Component(component string) ContextLogger {
+	return ContextLogger{component: component}
+}
+
+// Component returns a copy of the logger for the entries of component.
+func (l ContextLogger) Component(component string) ContextLogger {
+	l.component = component
+	return l
+}
+
+func (l ContextLogger) Debug(message string, tags ...string) {
+	log(l.component, logrus.DebugLevel, message, l.with(tags))
+}
+
+func (l ContextLogger) Info(message string, tags ...string) {
+	log(l.component, logrus.InfoLevel, message, l.with(tags))
+}
+
+func (l ContextLogger) Warn(message string, tags ...string) {
+	log(l.component, logrus.WarnLevel, message, l.with(tags))
+}
+
+func (l ContextLogger) Error(message string, err error, tags ...string) {
+	log(l.component, logrus.ErrorLevel, errorMessage(message, err), l.with(tags))
+}
+
+func (l ContextLogger) Panic(message string, err error, tags ...string) {
+	log(l.component, logrus.PanicLevel, panicMessage(message, err), l.with(tags))
+}
+
+func (l ContextLogger) with(tags []string) []string {
+	all := make([]string, 0, len(tags)+len(l.tags))
+	return append(append(all, tags...), l.tags...)
 }
 
+// GetOut returns a writer to the sinks of the logger.
 func GetOut() io.Writer {
-	return Log.Out
+	return out
+}
+
+// Close flushes the sinks and closes them, logging to stderr afterwards.
+func Close() error {
+	if out == nil {
+		return nil
+	}
+	return closeSinks(out.swap(nil, []Sink{&writerSink{w: os.Stderr}}))
 }
 
 func buildLogEntryWithMessage(tags []string, message string) (*logrus.Entry, string) {
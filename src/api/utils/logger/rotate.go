@@ -0,0 +1,173 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	dirMode  = 0750
+	fileMode = 0640
+
+	backupTimeFormat = "20060102T150405.000"
+	// rotateRetryInterval is how long a failed rotation waits before being retried, writing to the current file.
+	rotateRetryInterval = time.Minute
+)
+
+// Rotation rotates the log file when it grows over MaxSizeMB or every Interval, zero disables either.
+// The rotated files past MaxBackups or older than MaxAge are removed, zero keeps them.
+type Rotation struct {
+	MaxSizeMB  int
+	Interval   time.Duration
+	MaxBackups int
+	MaxAge     time.Duration
+}
+
+// rotatingFile is a file sink renaming the file to path-<timestamp>.ext when it has to rotate.
+type rotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	rotation Rotation
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	retryAt  time.Time
+}
+
+func openRotatingFile(dir string, name string, rotation Rotation) (*rotatingFile, error) {
+	if name == "" {
+		return nil, fmt.Errorf("the file log sink requires a log file name")
+	}
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return nil, err
+	}
+	r := &rotatingFile{path: filepath.Join(dir, name), rotation: rotation}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, fileMode)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.size, r.openedAt = file, info.Size(), time.Now()
+	return nil
+}
+
+func (r *rotatingFile) Write(level logrus.Level, line []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return os.ErrClosed
+	}
+	if r.shouldRotate(len(line)) {
+		if err := r.rotate(); err != nil {
+			// the line is still written to the current file
+			fmt.Fprintf(os.Stderr, "Failed to rotate log file %s, retrying in %s: %v\n", r.path, rotateRetryInterval, err)
+			r.retryAt = time.Now().Add(rotateRetryInterval)
+		}
+	}
+	n, err := r.file.Write(line)
+	r.size += int64(n)
+	return err
+}
+
+func (r *rotatingFile) shouldRotate(next int) bool {
+	if r.size == 0 || time.Now().Before(r.retryAt) {
+		return false
+	}
+	if max := int64(r.rotation.MaxSizeMB) * 1024 * 1024; max > 0 && r.size+int64(next) > max {
+		return true
+	}
+	return r.rotation.Interval > 0 && time.Since(r.openedAt) >= r.rotation.Interval
+}
+
+// rotate renames the file to a backup and opens a new file at the path. The current file is only closed once
+// another one is open: when the rename fails the path is reopened, and when the new file can't be opened the
+// backup is renamed back, so the lines are never dropped.
+func (r *rotatingFile) rotate() error {
+	backup := r.backupName(time.Now())
+	if err := os.Rename(r.path, backup); err != nil {
+		if reopenErr := r.reopen(); reopenErr != nil {
+			return fmt.Errorf("%v, reopening it: %v", err, reopenErr)
+		}
+		return err
+	}
+	if err := r.reopen(); err != nil {
+		if renameErr := os.Rename(backup, r.path); renameErr != nil {
+			return fmt.Errorf("%v, restoring it: %v", err, renameErr)
+		}
+		return err
+	}
+	r.prune()
+	return nil
+}
+
+// reopen opens the file at the path, closing the current one once it's replaced.
+func (r *rotatingFile) reopen() error {
+	previous := r.file
+	if err := r.open(); err != nil {
+		return err
+	}
+	previous.Close()
+	return nil
+}
+
+func (r *rotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(r.path)
+	return strings.TrimSuffix(r.path, ext) + "-" + t.UTC().Format(backupTimeFormat) + ext
+}
+
+// prune removes the backups beyond the retention, the newest backups are kept.
+func (r *rotatingFile) prune() {
+	ext := filepath.Ext(r.path)
+	backups, err := filepath.Glob(strings.TrimSuffix(r.path, ext) + "-*" + ext)
+	if err != nil {
+		return
+	}
+	// the timestamp in the names sorts them chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	for i, backup := range backups {
+		expired := r.rotation.MaxBackups > 0 && i >= r.rotation.MaxBackups
+		if !expired && r.rotation.MaxAge > 0 {
+			if info, err := os.Stat(backup); err == nil && time.Since(info.ModTime()) > r.rotation.MaxAge {
+				expired = true
+			}
+		}
+		if expired {
+			if err := os.Remove(backup); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to remove log backup %s: %v\n", backup, err)
+			}
+		}
+	}
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	file := r.file
+	r.file = nil
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
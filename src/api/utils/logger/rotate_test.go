@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	r, err := openRotatingFile(dir, "api.log", Rotation{Interval: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for _, line := range []string{"first\n", "second\n"} {
+		if err := r.Write(logrus.InfoLevel, []byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := readLog(t, r.path); got != "second\n" {
+		t.Errorf("log file holds %q, want the line written after the rotation", got)
+	}
+	if backups, _ := filepath.Glob(filepath.Join(dir, "api-*.log")); len(backups) != 1 || readLog(t, backups[0]) != "first\n" {
+		t.Errorf("got backups %q, want the first line rotated", backups)
+	}
+}
+
+func TestRotateFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		// break makes the directory of the log unwritable, returning the function restoring it
+		breakDir func(dir string) func()
+		root     bool
+	}{
+		{"unwritable directory", func(dir string) func() {
+			os.Chmod(dir, 0500)
+			return func() { os.Chmod(dir, dirMode) }
+		}, false},
+		{"removed directory", func(dir string) func() {
+			os.RemoveAll(dir)
+			return func() { os.MkdirAll(dir, dirMode) }
+		}, true},
+	}
+	for _, tt := range tests {
+		if !tt.root && os.Geteuid() == 0 {
+			t.Logf("%s: skipped, root can write to the directory", tt.name)
+			continue
+		}
+		dir := filepath.Join(t.TempDir(), "logs")
+		r, err := openRotatingFile(dir, "api.log", Rotation{Interval: time.Nanosecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Write(logrus.InfoLevel, []byte("first\n")); err != nil {
+			t.Fatal(err)
+		}
+
+		restore := tt.breakDir(dir)
+		if err := r.Write(logrus.InfoLevel, []byte("second\n")); err != nil {
+			t.Errorf("%s: write failed with %v, want the line written to the current file", tt.name, err)
+		}
+		if r.file == nil || !r.retryAt.After(time.Now()) {
+			t.Errorf("%s: got file %v retrying at %s, want the file kept and the rotation retried later", tt.name, r.file, r.retryAt)
+		}
+		restore()
+
+		// once the directory is back the rotation is retried
+		r.retryAt = time.Time{}
+		if err := r.Write(logrus.InfoLevel, []byte("third\n")); err != nil {
+			t.Errorf("%s: write after restoring the directory failed with %v", tt.name, err)
+		}
+		if got := readLog(t, r.path); !strings.HasSuffix(got, "third\n") {
+			t.Errorf("%s: log file holds %q, want the lines written after restoring the directory", tt.name, got)
+		}
+		r.Close()
+	}
+}
+
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/lnpay-wrapper-api-go/src/api/utils/redact"
+	"github.com/sirupsen/logrus"
+	prefixed "github.com/x-cray/logrus-prefixed-formatter"
+)
+
+const (
+	SinkStderr = "stderr"
+	SinkFile   = "file"
+	SinkSyslog = "syslog"
+
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Sink receives the formatted log entries.
+type Sink interface {
+	Write(level logrus.Level, line []byte) error
+	Close() error
+}
+
+// writerSink writes the entries to a writer it doesn't own, like stderr.
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *writerSink) Write(level logrus.Level, line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.w.Write(line)
+	return err
+}
+
+func (s *writerSink) Close() error {
+	return nil
+}
+
+// output formats the entries once, masks the secrets and writes them to every sink. It is installed as a hook so the sinks
+// know the level of each entry, the logger itself writes nowhere.
+type output struct {
+	mu        sync.RWMutex
+	formatter logrus.Formatter
+	sinks     []Sink
+}
+
+func (o *output) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (o *output) Fire(entry *logrus.Entry) error {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	line, err := o.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	line = redact.Bytes(line)
+	for _, sink := range o.sinks {
+		if err := sink.Write(entry.Level, line); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write log entry: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// Write writes raw lines, like the ones of the gin logger, to every sink at info level.
+func (o *output) Write(p []byte) (int, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	line := redact.Bytes(p)
+	for _, sink := range o.sinks {
+		if err := sink.Write(logrus.InfoLevel, line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// swap replaces the sinks, and the formatter unless it's nil, returning the previous sinks to be closed.
+func (o *output) swap(formatter logrus.Formatter, sinks []Sink) []Sink {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	previous := o.sinks
+	if formatter != nil {
+		o.formatter = formatter
+	}
+	o.sinks = sinks
+	return previous
+}
+
+// discardFormatter skips formatting the entries the logger writes to io.Discard, output formats them.
+type discardFormatter struct{}
+
+func (discardFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return nil, nil
+}
+
+func newFormatter(format string) logrus.Formatter {
+	if format == FormatJSON {
+		return &logrus.JSONFormatter{
+			FieldMap: logrus.FieldMap{logrus.FieldKeyTime: "time", logrus.FieldKeyMsg: "message"},
+		}
+	}
+	return &prefixed.TextFormatter{ForceFormatting: true, FullTimestamp: true}
+}
+
+func newSinks(conf ConfigureLog) ([]Sink, error) {
+	names := conf.Sinks
+	if len(names) == 0 {
+		names = []string{SinkFile}
+	}
+	sinks := make([]Sink, 0, len(names))
+	for _, name := range names {
+		sink, err := newSink(name, conf)
+		if err != nil {
+			closeSinks(sinks)
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+func newSink(name string, conf ConfigureLog) (Sink, error) {
+	switch name {
+	case SinkStderr:
+		return &writerSink{w: os.Stderr}, nil
+	case SinkFile:
+		return openRotatingFile(conf.LoggingPath, conf.LoggingFile, conf.Rotation)
+	case SinkSyslog:
+		return newSyslogSink(conf.Syslog)
+	}
+	return nil, fmt.Errorf("unknown log sink %q", name)
+}
+
+func closeSinks(sinks []Sink) error {
+	var first error
+	for _, sink := range sinks {
+		if err := sink.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,200 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// facilityLocal0 is the syslog facility of the entries.
+const facilityLocal0 = 16
+
+// Syslog is the syslog server receiving the entries. Network is udp, tcp, unix or unixgram.
+type Syslog struct {
+	Network string
+	Addr    string
+	Tag     string
+}
+
+var syslogSeverities = map[logrus.Level]int{
+	logrus.PanicLevel: 2,
+	logrus.FatalLevel: 2,
+	logrus.ErrorLevel: 3,
+	logrus.WarnLevel:  4,
+	logrus.InfoLevel:  6,
+	logrus.DebugLevel: 7,
+	logrus.TraceLevel: 7,
+}
+
+const (
+	// syslogQueueSize is how many entries wait to be sent, the new entries are dropped once it's full.
+	syslogQueueSize    = 1024
+	syslogDialTimeout  = 5 * time.Second
+	syslogWriteTimeout = time.Second
+	// syslogMaxBackoff bounds the wait between dials while the server is down.
+	syslogMaxBackoff = 30 * time.Second
+)
+
+// syslogSink sends RFC 5424 messages, framed with their length over stream connections (RFC 6587).
+// The messages are sent from a goroutine so logging never waits for the server: while it's down or slow
+// the queue fills up and the entries are dropped, and the connection is dialed again with a backoff.
+type syslogSink struct {
+	// dropped counts the entries dropped since the last message sent, first to be aligned for atomic.
+	dropped  uint64
+	conf     Syslog
+	hostname string
+	queue    chan []byte
+	done     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+
+	// conn, backoff and retryAt belong to the goroutine sending the messages.
+	conn    net.Conn
+	backoff time.Duration
+	retryAt time.Time
+}
+
+func newSyslogSink(conf Syslog) (*syslogSink, error) {
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	s := &syslogSink{
+		conf:     conf,
+		hostname: hostname,
+		queue:    make(chan []byte, syslogQueueSize),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	if err := s.dial(); err != nil {
+		return nil, err
+	}
+	go s.run()
+	return s, nil
+}
+
+func (s *syslogSink) dial() error {
+	conn, err := net.DialTimeout(s.conf.Network, s.conf.Addr, syslogDialTimeout)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *syslogSink) stream() bool {
+	return s.conf.Network == "tcp" || s.conf.Network == "unix"
+}
+
+// Write queues the entry, dropping it when the queue is full.
+func (s *syslogSink) Write(level logrus.Level, line []byte) error {
+	select {
+	case <-s.done:
+		return errors.New("syslog sink closed")
+	default:
+	}
+	select {
+	case s.queue <- s.format(level, line):
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+	return nil
+}
+
+func (s *syslogSink) run() {
+	defer close(s.stopped)
+	for {
+		select {
+		case message := <-s.queue:
+			s.send(message)
+		case <-s.done:
+			for {
+				select {
+				case message := <-s.queue:
+					s.send(message)
+				default:
+					if s.conn != nil {
+						s.conn.Close()
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// send writes the message, dialing again once when the connection failed. While the server is down
+// the messages are dropped and the dials back off.
+func (s *syslogSink) send(message []byte) {
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if time.Now().Before(s.retryAt) {
+				break
+			}
+			if err := s.dial(); err != nil {
+				s.fail(err)
+				break
+			}
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+		if _, err := s.conn.Write(message); err != nil {
+			s.conn.Close()
+			s.conn = nil
+			continue
+		}
+		s.backoff = 0
+		if dropped := atomic.SwapUint64(&s.dropped, 0); dropped > 0 {
+			fmt.Fprintf(os.Stderr, "Syslog sink dropped %d log entries\n", dropped)
+		}
+		return
+	}
+	atomic.AddUint64(&s.dropped, 1)
+}
+
+func (s *syslogSink) fail(err error) {
+	s.backoff *= 2
+	if s.backoff < time.Second {
+		s.backoff = time.Second
+	}
+	if s.backoff > syslogMaxBackoff {
+		s.backoff = syslogMaxBackoff
+	}
+	s.retryAt = time.Now().Add(s.backoff)
+	fmt.Fprintf(os.Stderr, "Failed to dial syslog server %s, retrying in %s: %v\n", s.conf.Addr, s.backoff, err)
+}
+
+func (s *syslogSink) format(level logrus.Level, line []byte) []byte {
+	severity, ok := syslogSeverities[level]
+	if !ok {
+		severity = 6
+	}
+	tag := s.conf.Tag
+	if tag == "" {
+		tag = "-"
+	}
+	message := fmt.Sprintf("<%d>1 %s %s %s %d - - %s", facilityLocal0*8+severity,
+		time.Now().Format(time.RFC3339Nano), s.hostname, tag, os.Getpid(), bytes.TrimRight(line, "\n"))
+	if s.stream() {
+		message = fmt.Sprintf("%d %s", len(message), message)
+	}
+	return []byte(message)
+}
+
+// Close sends the queued messages and closes the connection, giving up waiting after syslogDialTimeout
+// so a slow server doesn't hold the reconfiguration or the shutdown.
+func (s *syslogSink) Close() error {
+	s.once.Do(func() { close(s.done) })
+	select {
+	case <-s.stopped:
+		return nil
+	case <-time.After(syslogDialTimeout):
+		return errors.New("timeout sending the queued syslog messages")
+	}
+}
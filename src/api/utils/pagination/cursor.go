@@ -0,0 +1,51 @@
+/**
+* @author mnunez
+ */
+
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the position where the next page of a listing starts.
+// Clients only see it as an opaque string.
+type Cursor struct {
+	// Page is the upstream page where the listing must resume.
+	Page int `json:"p"`
+	// CreatedAt and ID identify the last item already returned.
+	CreatedAt int    `json:"t"`
+	ID        string `json:"i"`
+	// Filters is a fingerprint of the filters the cursor was generated with.
+	Filters string `json:"f,omitempty"`
+}
+
+// Encode returns the opaque representation of the cursor.
+func (c Cursor) Encode() string {
+	bytes, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// Decode parses a cursor previously returned by Encode.
+func Decode(value string) (Cursor, error) {
+	var cursor Cursor
+	bytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(bytes, &cursor); err != nil || cursor.Page < 1 {
+		return cursor, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// Paging is the pagination information returned with every page.
+type Paging struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
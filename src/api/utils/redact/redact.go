@@ -0,0 +1,90 @@
+package redact
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Mask replaces the secrets.
+const Mask = "***"
+
+// DefaultFields are the fields whose values are always masked.
+var DefaultFields = []string{
+	"x-api-key",
+	"preimage",
+	"payment_preimage",
+	"lnpay_api_key",
+	"auth_bootstrap_key",
+}
+
+// keyPattern matches the lnpay api keys (pak_, sak_) and wallet access keys (waka_, waki_, wakr_).
+var keyPattern = regexp.MustCompile(`\b(?:pak|sak|waka|waki|wakr)_[A-Za-z0-9_]+`)
+
+var (
+	mu           sync.RWMutex
+	fieldPattern = compileFields(nil)
+)
+
+// Configure masks the values of fields, like the sensitive passThru fields of the invoices,
+// besides the DefaultFields.
+func Configure(fields []string) {
+	pattern := compileFields(fields)
+	mu.Lock()
+	fieldPattern = pattern
+	mu.Unlock()
+}
+
+// compileFields matches a field and its value in json documents, escaped or not, headers, tags and query strings:
+// "name":"value", name: value, name=value. Objects and arrays aren't masked.
+func compileFields(fields []string) *regexp.Regexp {
+	all := make([]string, 0, len(DefaultFields)+len(fields))
+	all = append(append(all, DefaultFields...), fields...)
+	names := make([]string, 0, len(all))
+	for _, field := range all {
+		if field = strings.TrimSpace(field); field != "" {
+			names = append(names, regexp.QuoteMeta(field))
+		}
+	}
+	return regexp.MustCompile(`(?i)(["\\]*\b(?:` + strings.Join(names, "|") + `)\b["\\]*\s*[:=]\s*["\\]*)([^"\\\s,;&{}\[\]]+)`)
+}
+
+// String masks the keys and the values of the sensitive fields found in s.
+func String(s string) string {
+	s = keyPattern.ReplaceAllStringFunc(s, Key)
+	mu.RLock()
+	pattern := fieldPattern
+	mu.RUnlock()
+	return pattern.ReplaceAllString(s, "${1}"+Mask)
+}
+
+// Bytes masks the keys and the values of the sensitive fields found in b.
+func Bytes(b []byte) []byte {
+	b = keyPattern.ReplaceAllFunc(b, func(key []byte) []byte {
+		return []byte(Key(string(key)))
+	})
+	mu.RLock()
+	pattern := fieldPattern
+	mu.RUnlock()
+	return pattern.ReplaceAll(b, []byte("${1}"+Mask))
+}
+
+// Key hides all but the prefix and the last characters of a key, enough to tell the keys apart.
+func Key(key string) string {
+	prefix := ""
+	if i := strings.Index(key, "_"); i >= 0 {
+		prefix, key = key[:i+1], key[i+1:]
+	}
+	if len(key) <= 8 {
+		return prefix + Mask
+	}
+	return prefix + Mask + key[len(key)-4:]
+}
+
+// Secret masks a whole secret, keeping it empty when it isn't set.
+func Secret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return Mask
+}
@@ -0,0 +1,49 @@
+package redact
+
+import "testing"
+
+func TestString(t *testing.T) {
+	defer Configure(nil)
+	Configure([]string{"card_number"})
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"api key", "using pak_O0iUMxk8kK_qUzkT4YKFvp1ZsUtp", "using pak_***sUtp"},
+		{"wallet key", "/v1/wallets/waki_qUzkT4YKFvp1ZsUtp/invoices", "/v1/wallets/waki_***sUtp/invoices"},
+		{"short key", "waka_abc", "waka_***"},
+		{"json field", `{"payment_preimage":"abc123","memo":"coffee"}`, `{"payment_preimage":"***","memo":"coffee"}`},
+		{"escaped json field", `{\"preimage\":\"abc123\"}`, `{\"preimage\":\"***\"}`},
+		{"header", "X-Api-Key: secret-value", "X-Api-Key: ***"},
+		{"tag", "lnpay_api_key:secret", "lnpay_api_key:***"},
+		{"query string", "?card_number=4111&amount=1", "?card_number=***&amount=1"},
+		{"configured field in json", `{"card_number": "4111"}`, `{"card_number": "***"}`},
+		{"field in a word", `{"preimages_count":2}`, `{"preimages_count":2}`},
+		{"object value", `{"preimage":{"a":1}}`, `{"preimage":{"a":1}}`},
+		{"nothing secret", "GET /health 200", "GET /health 200"},
+	}
+	for _, tt := range tests {
+		if got := String(tt.in); got != tt.want {
+			t.Errorf("%s: String(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+		if got := string(Bytes([]byte(tt.in))); got != tt.want {
+			t.Errorf("%s: Bytes(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestConfigureReplacesTheFields(t *testing.T) {
+	defer Configure(nil)
+	Configure([]string{"card_number"})
+	Configure([]string{"cvv"})
+	if got := String("card_number=4111 cvv=123 preimage=abc"); got != "card_number=4111 cvv=*** preimage=***" {
+		t.Errorf("got %q, want only cvv and the default fields masked", got)
+	}
+}
+
+func TestSecret(t *testing.T) {
+	if Secret("") != "" || Secret("s3cret") != Mask {
+		t.Errorf("Secret masks %q and %q", Secret(""), Secret("s3cret"))
+	}
+}
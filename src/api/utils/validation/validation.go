@@ -0,0 +1,199 @@
+/**
+* @author mnunez
+ */
+
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/lnpay-wrapper-api-go/src/api/lnpay"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+)
+
+// Limits bounds the amounts accepted by the amount rule.
+type Limits struct {
+	MinAmount int64
+	MaxAmount int64
+}
+
+var (
+	limitsMu sync.RWMutex
+	limits   Limits
+)
+
+// Register adds the custom rules to the validator used by gin binding:
+//
+//	bolt11     a BOLT11 payment request
+//	walletkey  a wallet access key or wallet id
+//	b64hash    a base64 encoded sha256 hash
+//	amount     an amount in satoshis within the configured limits
+//	maxbytes   a string at most param bytes long, e.g. a memo bounded by the invoice encoding
+func Register(l Limits) error {
+	SetLimits(l)
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("gin validator engine is not go-playground/validator")
+	}
+
+	v.RegisterTagNameFunc(jsonFieldName)
+	rules := map[string]validator.Func{
+		"bolt11":    stringRule(lnpay.IsPaymentRequest),
+		"walletkey": stringRule(lnpay.IsWalletKey),
+		"b64hash":   stringRule(lnpay.IsDescriptionHash),
+		"amount":    amountRule,
+		"maxbytes":  maxBytesRule,
+	}
+	for tag, rule := range rules {
+		if err := v.RegisterValidation(tag, rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetLimits changes the limits of the amount rule.
+func SetLimits(l Limits) {
+	limitsMu.Lock()
+	limits = l
+	limitsMu.Unlock()
+}
+
+func currentLimits() Limits {
+	limitsMu.RLock()
+	defer limitsMu.RUnlock()
+	return limits
+}
+
+// IsWalletKey validates a wallet key outside of a bound struct, e.g. a path param.
+func IsWalletKey(key string) bool {
+	return lnpay.IsWalletKey(key)
+}
+
+// FromBindingError converts the error returned by gin binding into a validation ApiError
+// with one cause per invalid field.
+func FromBindingError(err error) apierrors.ApiError {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return apierrors.Wrap(apierrors.NewBadRequestApiError("Invalid request: "+err.Error()), err)
+	}
+
+	causes := make([]apierrors.FieldCause, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		causes = append(causes, apierrors.FieldCause{
+			Field:   fieldPath(fieldErr),
+			Code:    fieldErr.Tag(),
+			Message: message(fieldErr),
+		})
+	}
+	return apierrors.Wrap(apierrors.NewFieldValidationApiError("Invalid request", causes...), err)
+}
+
+func stringRule(valid func(string) bool) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		return valid(fl.Field().String())
+	}
+}
+
+func amountRule(fl validator.FieldLevel) bool {
+	var amount int64
+	switch fl.Field().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		amount = fl.Field().Int()
+	default:
+		return false
+	}
+	l := currentLimits()
+	if amount < l.MinAmount {
+		return false
+	}
+	return l.MaxAmount <= 0 || amount <= l.MaxAmount
+}
+
+func maxBytesRule(fl validator.FieldLevel) bool {
+	max, err := strconv.Atoi(fl.Param())
+	if err != nil || fl.Field().Kind() != reflect.String {
+		return false
+	}
+	return len(fl.Field().String()) <= max
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// fieldPath drops the struct name from the namespace of the field, e.g. PayRequest.passThru.x
+func fieldPath(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+// lengthUnit is what max and min count for the field: the characters of a string, the items of a
+// collection and nothing for a number, whose value is compared.
+func lengthUnit(fieldErr validator.FieldError) string {
+	plural := "s"
+	if fieldErr.Param() == "1" {
+		plural = ""
+	}
+	switch fieldErr.Kind() {
+	case reflect.String:
+		return " character" + plural + " long"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " item" + plural
+	}
+	return ""
+}
+
+func message(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "bolt11":
+		return "must be a BOLT11 payment request"
+	case "walletkey":
+		return "must be a wallet key or id"
+	case "b64hash":
+		return "must be a base64 encoded sha256 hash"
+	case "amount":
+		l := currentLimits()
+		if l.MaxAmount > 0 {
+			return fmt.Sprintf("must be between %d and %d satoshis", l.MinAmount, l.MaxAmount)
+		}
+		return fmt.Sprintf("must be at least %d satoshis", l.MinAmount)
+	case "excluded_with":
+		return "can't be set together with " + strings.ToLower(fieldErr.Param())
+	case "max":
+		return "must be at most " + fieldErr.Param() + lengthUnit(fieldErr)
+	case "maxbytes":
+		if fieldErr.Param() == "1" {
+			return "must be at most 1 byte long"
+		}
+		return "must be at most " + fieldErr.Param() + " bytes long"
+	case "min", "gte":
+		return "must be at least " + fieldErr.Param() + lengthUnit(fieldErr)
+	case "gt":
+		return "must be greater than " + fieldErr.Param()
+	case "oneof":
+		return "must be one of " + fieldErr.Param()
+	}
+	return "failed the " + fieldErr.Tag() + " validation"
+}
@@ -0,0 +1,124 @@
+package validation
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/lnpay-wrapper-api-go/src/api/utils/apierrors"
+)
+
+func TestMain(m *testing.M) {
+	if err := Register(Limits{MinAmount: 1, MaxAmount: 1000}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// causes validates request with the gin validator and returns the causes of the failure.
+func causes(t *testing.T, request interface{}) []apierrors.FieldCause {
+	t.Helper()
+	err := binding.Validator.ValidateStruct(request)
+	if err == nil {
+		return nil
+	}
+	return FromBindingError(err).Cause().FieldCauses()
+}
+
+func TestLengthMessages(t *testing.T) {
+	type request struct {
+		Memo   string   `json:"memo" binding:"max=5"`
+		Label  string   `json:"label" binding:"min=1"`
+		Scopes []string `json:"scopes" binding:"min=1,max=2"`
+		Limit  int      `json:"limit" binding:"max=100"`
+	}
+	tests := []struct {
+		name    string
+		request request
+		field   string
+		message string
+	}{
+		{"long string", request{Memo: "too long", Label: "x", Scopes: []string{"read"}}, "memo", "must be at most 5 characters long"},
+		{"short string", request{Scopes: []string{"read"}}, "label", "must be at least 1 character long"},
+		{"few items", request{Label: "x", Scopes: []string{}}, "scopes", "must be at least 1 item"},
+		{"many items", request{Label: "x", Scopes: []string{"read", "pay", "admin"}}, "scopes", "must be at most 2 items"},
+		{"number", request{Label: "x", Scopes: []string{"read"}, Limit: 101}, "limit", "must be at most 100"},
+	}
+	for _, tt := range tests {
+		got := causes(t, tt.request)
+		if len(got) != 1 || got[0].Field != tt.field || got[0].Message != tt.message {
+			t.Errorf("%s: got %+v, want %s %q", tt.name, got, tt.field, tt.message)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	type request struct {
+		PaymentRequest string `json:"payment_request" binding:"omitempty,bolt11"`
+		Wallet         string `json:"wallet" binding:"omitempty,walletkey"`
+		Hash           string `json:"hash" binding:"omitempty,b64hash"`
+		Amount         int64  `json:"amount" binding:"amount"`
+		Memo           string `json:"memo" binding:"maxbytes=6"`
+	}
+	valid := request{Amount: 1}
+	tests := []struct {
+		name    string
+		change  func(r *request)
+		code    string
+		message string
+	}{
+		{"mainnet invoice", func(r *request) { r.PaymentRequest = "lnbc10u1pwzj6u8pp5qqqsyqcyq5rqwzqfqypqhp58yjmdan79s6qqdhdzgyn" }, "", ""},
+		{"testnet invoice with scheme", func(r *request) { r.PaymentRequest = "lightning:LNTB1PWZJ6U8PP5QQQSYQCYQ5RQWZQFQYPQ" }, "", ""},
+		{"not an invoice", func(r *request) { r.PaymentRequest = "lnbc1bcd" }, "bolt11", "must be a BOLT11 payment request"},
+		{"wrong network prefix", func(r *request) { r.PaymentRequest = "lnxx1pwzj6u8pp5qqqsyqcyq" }, "bolt11", "must be a BOLT11 payment request"},
+		{"invoice key", func(r *request) { r.Wallet = "waki_qUzkT4YKFvp1ZsUtp" }, "", ""},
+		{"wallet id", func(r *request) { r.Wallet = "wal_qUzkT4YKFvp1ZsUtp" }, "", ""},
+		{"api key", func(r *request) { r.Wallet = "pak_qUzkT4YKFvp1ZsUtp" }, "walletkey", "must be a wallet key or id"},
+		{"key with symbols", func(r *request) { r.Wallet = "waka_abc/../x" }, "walletkey", "must be a wallet key or id"},
+		{"sha256 hash", func(r *request) { r.Hash = "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=" }, "", ""},
+		{"short hash", func(r *request) { r.Hash = "YWJj" }, "b64hash", "must be a base64 encoded sha256 hash"},
+		{"max amount", func(r *request) { r.Amount = 1000 }, "", ""},
+		{"zero amount", func(r *request) { r.Amount = 0 }, "amount", "must be between 1 and 1000 satoshis"},
+		{"amount over the limit", func(r *request) { r.Amount = 1001 }, "amount", "must be between 1 and 1000 satoshis"},
+		{"memo bytes", func(r *request) { r.Memo = "ééé" }, "", ""},
+		{"memo over the bytes", func(r *request) { r.Memo = "éééé" }, "maxbytes", "must be at most 6 bytes long"},
+	}
+	for _, tt := range tests {
+		r := valid
+		tt.change(&r)
+		got := causes(t, r)
+		if tt.code == "" {
+			if len(got) != 0 {
+				t.Errorf("%s: got %+v, want valid", tt.name, got)
+			}
+			continue
+		}
+		if len(got) != 1 || got[0].Code != tt.code || got[0].Message != tt.message {
+			t.Errorf("%s: got %+v, want %s %q", tt.name, got, tt.code, tt.message)
+		}
+	}
+}
+
+func TestSetLimits(t *testing.T) {
+	defer SetLimits(Limits{MinAmount: 1, MaxAmount: 1000})
+	type request struct {
+		Amount int64 `json:"amount" binding:"amount"`
+	}
+	tests := []struct {
+		limits  Limits
+		amount  int64
+		message string
+	}{
+		{Limits{MinAmount: 10, MaxAmount: 20}, 9, "must be between 10 and 20 satoshis"},
+		{Limits{MinAmount: 10, MaxAmount: 20}, 20, ""},
+		{Limits{MinAmount: 10}, 1 << 40, ""},
+		{Limits{MinAmount: 10}, 5, "must be at least 10 satoshis"},
+	}
+	for _, tt := range tests {
+		SetLimits(tt.limits)
+		got := causes(t, request{Amount: tt.amount})
+		if tt.message == "" && len(got) != 0 || tt.message != "" && (len(got) != 1 || got[0].Message != tt.message) {
+			t.Errorf("%+v with %d: got %+v, want %q", tt.limits, tt.amount, got, tt.message)
+		}
+	}
+}